@@ -0,0 +1,89 @@
+// Package atomicfile replaces files through temporary ones, so a file is
+// either the old one or the complete new one, even if the write is cancelled
+// or the device loses power.
+package atomicfile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes data to path, see Write.
+func WriteFile(path string, data []byte) error {
+	return Write(path, bytes.NewReader(data), nil)
+}
+
+// Stage has fill write a file at a unique temporary name next to path, e.g.
+// through Write, and renames it over path, creating the directory when
+// missing. When fill fails the temporary file is removed and path is left
+// untouched.
+func Stage(path string, fill func(tmp string) error) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := fill(tmp.Name()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Write writes r to a uniquely named temporary file next to path, syncs it
+// and renames it over path, creating the directory when missing. before, when
+// set, is called with the name of the complete temporary file before the
+// rename, to check it or process it in place. When anything fails the
+// temporary file is removed and path is left untouched.
+func Write(path string, r io.Reader, before func(tmp string) error) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		// Removing after the rename fails harmlessly
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if before != nil {
+		if err := before(tmp.Name()); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
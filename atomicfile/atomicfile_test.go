@@ -0,0 +1,95 @@
+package atomicfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	beforeErr := errors.New("check failed")
+	tests := []struct {
+		name      string
+		before    func(tmp string) error
+		expected  string
+		expectErr error
+	}{
+		{name: "Complete file", expected: "new"},
+		{name: "Failed check", before: func(string) error { return beforeErr }, expected: "old", expectErr: beforeErr},
+		{
+			name:     "Processed in place",
+			before:   func(tmp string) error { return WriteFile(tmp, []byte("processed")) },
+			expected: "processed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "game.png")
+			if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			err := Write(path, strings.NewReader("new"), tt.before)
+			if !errors.Is(err, tt.expectErr) {
+				t.Errorf("expected error %v, got %v", tt.expectErr, err)
+			}
+
+			info, _ := os.Stat(path)
+			if data, _ := os.ReadFile(path); string(data) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, data)
+			}
+			if tt.expectErr == nil && info.Mode().Perm() != 0644 {
+				t.Errorf("expected mode 0644, got %v", info.Mode().Perm())
+			}
+
+			// Temporary files never stay behind
+			if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+				t.Errorf("expected only game.png, got %v", entries)
+			}
+		})
+	}
+}
+
+func TestWriteFileCreatesDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "games", "game.json")
+	if err := WriteFile(path, []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{}" {
+		t.Errorf("expected the file to be written, got %q", data)
+	}
+}
+
+func TestStage(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "game.png")
+	_ = os.WriteFile(path, []byte("old"), 0644)
+
+	fillErr := errors.New("fill failed")
+	err := Stage(path, func(tmp string) error {
+		if err := WriteFile(tmp, []byte("new")); err != nil {
+			return err
+		}
+		return fillErr
+	})
+	if !errors.Is(err, fillErr) {
+		t.Errorf("expected error %v, got %v", fillErr, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Errorf("expected the file to be kept, got %q", data)
+	}
+
+	if err := Stage(path, func(tmp string) error { return WriteFile(tmp, []byte("new")) }); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Errorf("expected the file to be replaced, got %q", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected only game.png, got %v", entries)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/veandco/go-sdl2/ttf"
@@ -19,43 +20,148 @@ type FontColors struct {
 }
 
 type SystemSettings struct {
-	ID        string `yaml:"id"`
-	Name      string `yaml:"name"`
-	OutputDir string `yaml:"output-dir,omitempty"`
+	ID        string   `yaml:"id"`
+	Name      string   `yaml:"name"`
+	OutputDir string   `yaml:"output-dir,omitempty"`
+	Provider  string   `yaml:"provider,omitempty"`
+	Overrides string   `yaml:"overrides,omitempty"`
+	Exports   []string `yaml:"exports,omitempty"`
+	Playlist  string   `yaml:"playlist,omitempty"`
 }
 
 type ScrapeMedia struct {
-	Type                string   `yaml:"type"`
-	Regions             []string `yaml:"regions"`
-	Width               int32    `yaml:"width"`
-	Height              int32    `yaml:"height"`
-	IgnoreMissingRegion bool     `yaml:"ignore-missing-region"`
+	Type                MediaTypes `yaml:"type"`
+	Regions             []string   `yaml:"regions"`
+	Width               int32      `yaml:"width"`
+	Height              int32      `yaml:"height"`
+	IgnoreMissingRegion bool       `yaml:"ignore-missing-region"`
+	Dir                 string     `yaml:"dir,omitempty"`
+	Layout              string     `yaml:"layout,omitempty"`
+}
+
+// MediaTypes is an ordered fallback list of media types, the first one a game
+// has is downloaded. A single type may be given as a plain value.
+type MediaTypes []string
+
+func (t *MediaTypes) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*t = MediaTypes{value.Value}
+		return nil
+	}
+
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+func (t MediaTypes) MarshalYAML() (any, error) {
+	if len(t) == 1 {
+		return t[0], nil
+	}
+	return []string(t), nil
+}
+
+func (t MediaTypes) String() string {
+	return strings.Join(t, ", ")
+}
+
+// MediaList holds every media downloaded for a game. A single mapping is read
+// as a list of one, as in older config files.
+type MediaList []ScrapeMedia
+
+func (m *MediaList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.MappingNode {
+		var media ScrapeMedia
+		if err := value.Decode(&media); err != nil {
+			return err
+		}
+		*m = MediaList{media}
+		return nil
+	}
+
+	var list []ScrapeMedia
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*m = list
+	return nil
 }
 
 type scraperConfig struct {
-	Username string      `yaml:"username"`
-	Password string      `yaml:"password"`
-	Media    ScrapeMedia `yaml:"media"`
-	Threads  int         `yaml:"threads"`
+	Username    string       `yaml:"username"`
+	Password    string       `yaml:"password"`
+	Media       MediaList    `yaml:"media"`
+	Threads     int          `yaml:"threads"`
+	MaxAttempts int          `yaml:"max-attempts,omitempty"`
+	Search      searchConfig `yaml:"search,omitempty"`
+	Languages   []string     `yaml:"languages,omitempty"`
+}
+
+type searchConfig struct {
+	Disabled      bool    `yaml:"disabled,omitempty"`
+	MinConfidence float64 `yaml:"min-confidence,omitempty"`
 }
 
 type scraperSystem struct {
-	ID        string `yaml:"id"`
-	Name      string `yaml:"name"`
-	OutputDir string `yaml:"output-dir,omitempty"`
-	Dir       string `yaml:"dir"`
+	ID        string   `yaml:"id"`
+	Name      string   `yaml:"name"`
+	OutputDir string   `yaml:"output-dir,omitempty"`
+	Dir       string   `yaml:"dir"`
+	Provider  string   `yaml:"provider,omitempty"`
+	Overrides string   `yaml:"overrides,omitempty"`
+	Exports   []string `yaml:"exports,omitempty"`
+	Playlist  string   `yaml:"playlist,omitempty"`
+}
+
+type libretroConfig struct {
+	Source string `yaml:"source"`
+}
+
+type retroarchConfig struct {
+	Playlists  string `yaml:"playlists"`
+	Thumbnails string `yaml:"thumbnails,omitempty"`
+}
+
+type cacheConfig struct {
+	Dir      string        `yaml:"dir"`
+	TTL      time.Duration `yaml:"ttl"`
+	Disabled bool          `yaml:"disabled,omitempty"`
+}
+
+// refreshConfig selects the files already scraped that are downloaded again.
+type refreshConfig struct {
+	// Mode is one of none, all, older, smaller or changed, empty is none
+	Mode string `yaml:"mode,omitempty"`
+	// MaxAge is in days, for the older mode
+	MaxAge    int `yaml:"max-age,omitempty"`
+	MinWidth  int `yaml:"min-width,omitempty"`
+	MinHeight int `yaml:"min-height,omitempty"`
 }
 
 type boxartConfig struct {
-	Dir    string `yaml:"dir"`
-	Width  int    `yaml:"width"`
-	Height int    `yaml:"height"`
+	Dir     string `yaml:"dir"`
+	Width   int    `yaml:"width"`
+	Height  int    `yaml:"height"`
+	Resize  string `yaml:"resize,omitempty"`
+	Pad     bool   `yaml:"pad,omitempty"`
+	Format  string `yaml:"format,omitempty"`
+	Quality int    `yaml:"quality,omitempty"`
 }
 type userConfigs struct {
 	Boxart                  boxartConfig    `yaml:"thumbnail"`
 	Roms                    string          `yaml:"roms"`
 	Logos                   string          `yaml:"logos"`
 	Screenscraper           scraperConfig   `yaml:"screenscraper"`
+	Libretro                libretroConfig  `yaml:"libretro,omitempty"`
+	RetroArch               retroarchConfig `yaml:"retroarch,omitempty"`
+	Cache                   cacheConfig     `yaml:"cache,omitempty"`
+	Overrides               string          `yaml:"overrides,omitempty"`
+	Exports                 []string        `yaml:"exports,omitempty"`
+	Sidecars                bool            `yaml:"sidecars,omitempty"`
+	Refresh                 refreshConfig   `yaml:"refresh,omitempty"`
 	Systems                 []scraperSystem `yaml:"systems"`
 	MaxScanDepth            int             `yaml:"max-scan-depth"`
 	ExcludeExtensions       []string        `yaml:"exclude-extensions"`
@@ -85,13 +191,27 @@ var (
 	Username           string
 	Password           string
 	Systems            map[string]SystemSettings
-	Media              ScrapeMedia
+	Media              = MediaList{{Type: MediaTypes{"box-3D"}}}
 	Boxart             = boxartConfig{
 		Width:  400,
 		Height: 580,
 		Dir:    "thumbnails",
 	}
+	Cache = cacheConfig{
+		Dir: "cache",
+		TTL: 30 * 24 * time.Hour,
+	}
+	Search                   = searchConfig{MinConfidence: 0.8}
+	Languages                = []string{"en"}
+	LibretroSource           = "https://thumbnails.libretro.com"
+	OverridesFile            string
+	Exports                  []string
+	RetroArch                retroarchConfig
+	Sidecars                 bool
+	Refresh                  = refreshConfig{MaxAge: 30}
+	BypassCache              bool
 	Threads                  = 1
+	MaxAttempts              = 3
 	MaxScanDepth             = 2
 	ExcludeExtensions        []string
 	defaultExcludeExtensions = []string{
@@ -134,8 +254,40 @@ func InitVars() {
 	Username = cfg.Screenscraper.Username
 	Password = cfg.Screenscraper.Password
 	Threads = cfg.Screenscraper.Threads
+	if cfg.Screenscraper.MaxAttempts > 0 {
+		MaxAttempts = cfg.Screenscraper.MaxAttempts
+	}
+	Search.Disabled = cfg.Screenscraper.Search.Disabled
+	if cfg.Screenscraper.Search.MinConfidence > 0 {
+		Search.MinConfidence = cfg.Screenscraper.Search.MinConfidence
+	}
+	if len(cfg.Screenscraper.Languages) > 0 {
+		Languages = cfg.Screenscraper.Languages
+	}
 	Systems = setSystems(cfg.Systems)
-	Media = cfg.Screenscraper.Media
+	if len(cfg.Screenscraper.Media) > 0 {
+		Media = cfg.Screenscraper.Media
+	}
+	if cfg.Libretro.Source != "" {
+		LibretroSource = cfg.Libretro.Source
+	}
+	if cfg.Cache.Dir != "" {
+		Cache.Dir = cfg.Cache.Dir
+	}
+	if cfg.Cache.TTL > 0 {
+		Cache.TTL = cfg.Cache.TTL
+	}
+	Cache.Disabled = cfg.Cache.Disabled
+	OverridesFile = cfg.Overrides
+	Exports = cfg.Exports
+	RetroArch = cfg.RetroArch
+	Sidecars = cfg.Sidecars
+	Refresh.Mode = cfg.Refresh.Mode
+	if cfg.Refresh.MaxAge > 0 {
+		Refresh.MaxAge = cfg.Refresh.MaxAge
+	}
+	Refresh.MinWidth = cfg.Refresh.MinWidth
+	Refresh.MinHeight = cfg.Refresh.MinHeight
 	Boxart = cfg.Boxart
 	BodyFont = nil
 	HeaderFont = nil
@@ -168,19 +320,61 @@ func setSystems(systems []scraperSystem) map[string]SystemSettings {
 			ID:        system.ID,
 			Name:      system.Name,
 			OutputDir: outputDir,
+			Provider:  system.Provider,
+			Overrides: system.Overrides,
+			Exports:   system.Exports,
+			Playlist:  system.Playlist,
 		}
 	}
 
 	return systemSettings
 }
 
-func ScrapedImgDir(outputDir string) string {
-	dir := strings.ReplaceAll(Boxart.Dir, "/", string(filepath.Separator))
+// ExportFormats returns the formats the games of the system are exported to,
+// the global ones unless the system sets its own.
+func (s SystemSettings) ExportFormats() []string {
+	if s.Exports != nil {
+		return s.Exports
+	}
+	return Exports
+}
+
+// ScrapedImgDir returns the output dir of the media for a system, the media
+// dir template falls back to thumbnail.dir.
+func ScrapedImgDir(media ScrapeMedia, outputDir string) string {
+	template := media.Dir
+	if template == "" {
+		template = Boxart.Dir
+	}
+	dir := strings.ReplaceAll(template, "/", string(filepath.Separator))
 	dir = strings.ReplaceAll(dir, "\\", string(filepath.Separator))
 	dir = strings.ReplaceAll(dir, "%SYSTEM%", outputDir)
 	return dir
 }
 
+// PreferredRegions returns the regions of the first media entry, used when
+// something other than a media needs a region order.
+func PreferredRegions() []string {
+	if len(Media) == 0 {
+		return nil
+	}
+	return Media[0].Regions
+}
+
+// CacheDir returns the cache directory, relative paths are resolved against the
+// directory of the configuration file.
+func CacheDir() string {
+	return ConfigPath(Cache.Dir)
+}
+
+// ConfigPath resolves a path from the config file relative to its directory.
+func ConfigPath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(ConfigFile), path)
+}
+
 func readConfigFile() (*userConfigs, error) {
 	var cfg *userConfigs
 	file, err := os.ReadFile(ConfigFile)
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"slices"
+	"testing"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestMediaListUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name: "single mapping",
+			input: `
+media:
+  type: box-3D
+  regions: [us]
+`,
+			expected: []string{"box-3D"},
+		},
+		{
+			name: "list",
+			input: `
+media:
+  - type: box-2D
+    dir: /Imgs/%SYSTEM%
+  - type: ss
+    dir: /Screenshots/%SYSTEM%
+`,
+			expected: []string{"box-2D", "ss"},
+		},
+		{
+			name: "type fallback list",
+			input: `
+media:
+  - type: [box-3D, box-2D, mixrbv2, ss]
+`,
+			expected: []string{"box-3D, box-2D, mixrbv2, ss"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg scraperConfig
+			if err := yaml.Unmarshal([]byte(tt.input), &cfg); err != nil {
+				t.Fatal(err)
+			}
+			if len(cfg.Media) != len(tt.expected) {
+				t.Fatalf("expected %d media, got %d", len(tt.expected), len(cfg.Media))
+			}
+			for i, media := range cfg.Media {
+				if media.Type.String() != tt.expected[i] {
+					t.Errorf("expected media type %s, got %s", tt.expected[i], media.Type)
+				}
+			}
+		})
+	}
+}
+
+func TestScrapedImgDir(t *testing.T) {
+	Boxart.Dir = "/Imgs/%SYSTEM%"
+
+	if dir := ScrapedImgDir(ScrapeMedia{}, "SFC"); dir != "/Imgs/SFC" {
+		t.Errorf("expected the thumbnail dir, got %s", dir)
+	}
+	if dir := ScrapedImgDir(ScrapeMedia{Dir: "/Screenshots/%SYSTEM%"}, "SFC"); dir != "/Screenshots/SFC" {
+		t.Errorf("expected the media dir, got %s", dir)
+	}
+}
+
+func TestExportFormats(t *testing.T) {
+	var cfg userConfigs
+	err := yaml.Unmarshal([]byte(`
+exports: [pegasus]
+systems:
+  - dir: SFC
+    exports: [gamelist, retroarch]
+  - dir: GB
+  - dir: MD
+    exports: []
+`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Exports = cfg.Exports
+	systems := setSystems(cfg.Systems)
+
+	tests := []struct {
+		dir      string
+		expected []string
+	}{
+		{"SFC", []string{"gamelist", "retroarch"}},
+		{"GB", []string{"pegasus"}},
+		{"MD", []string{}},
+	}
+	for _, tt := range tests {
+		if got := systems[tt.dir].ExportFormats(); !slices.Equal(got, tt.expected) {
+			t.Errorf("expected %s to export %v, got %v", tt.dir, tt.expected, got)
+		}
+	}
+}
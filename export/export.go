@@ -0,0 +1,121 @@
+// Package export writes what was scraped to the game lists of frontends.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/anibaldeboni/screech/scraper"
+)
+
+var (
+	UnknownExporterErr = errors.New("unknown export format")
+
+	exporters = map[string]Exporter{
+		"gamelist":  Gamelist{},
+		"miyoo":     Miyoo{},
+		"pegasus":   Pegasus{},
+		"retroarch": RetroArch{},
+	}
+)
+
+// Exporter writes the scraped games of a system in a frontend format. Files
+// already there are merged, keeping what the user set.
+type Exporter interface {
+	Export(system System, entries []Entry) error
+}
+
+// System is a scraped system dir.
+type System struct {
+	Dir  string
+	Name string
+	// Playlist is the libretro database name of the system, e.g.
+	// "Nintendo - Super Nintendo Entertainment System"
+	Playlist string
+}
+
+// Entry is a scraped rom with the media files saved for it, in the order of
+// the media config.
+type Entry struct {
+	RomPath string
+	Game    scraper.Game
+	Media   []File
+}
+
+// File is a media file of a rom.
+type File struct {
+	Type string
+	Path string
+}
+
+// label is the name frontends show for the entry.
+func (e Entry) label() string {
+	if e.Game.Name != "" {
+		return e.Game.Name
+	}
+	fileName := filepath.Base(e.RomPath)
+	return strings.TrimSuffix(fileName, filepath.Ext(fileName))
+}
+
+// GetExporter returns the exporter of a format.
+func GetExporter(format string) (Exporter, error) {
+	exporter, ok := exporters[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", UnknownExporterErr, format)
+	}
+	return exporter, nil
+}
+
+// Batch gathers the entries of a scraping run to export them once it's done.
+type Batch struct {
+	mu      sync.Mutex
+	systems map[string]*batchSystem
+}
+
+type batchSystem struct {
+	system  System
+	formats []string
+	entries []Entry
+}
+
+func NewBatch() *Batch {
+	return &Batch{systems: make(map[string]*batchSystem)}
+}
+
+// Add records an entry of a system to export in the given formats. Safe for
+// concurrent use.
+func (b *Batch) Add(system System, formats []string, entry Entry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.systems[system.Dir]
+	if !ok {
+		s = &batchSystem{system: system, formats: formats}
+		b.systems[system.Dir] = s
+	}
+	s.entries = append(s.entries, entry)
+}
+
+// Write exports the entries of every system in its formats.
+func (b *Batch) Write() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var errs []error
+	for _, s := range b.systems {
+		for _, format := range s.formats {
+			exporter, err := GetExporter(format)
+			if err == nil {
+				err = exporter.Export(s.system, s.entries)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.system.Name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
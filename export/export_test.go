@@ -0,0 +1,35 @@
+package export
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBatchWrite(t *testing.T) {
+	gamelistDir, pegasusDir := t.TempDir(), t.TempDir()
+
+	batch := NewBatch()
+	batch.Add(System{Dir: gamelistDir, Name: "SFC"}, []string{"gamelist"}, Entry{RomPath: filepath.Join(gamelistDir, "game.sfc")})
+	batch.Add(System{Dir: pegasusDir, Name: "GB"}, []string{"pegasus", "mame"}, Entry{RomPath: filepath.Join(pegasusDir, "game.gb")})
+
+	if err := batch.Write(); !errors.Is(err, UnknownExporterErr) {
+		t.Errorf("expected UnknownExporterErr, got %v", err)
+	}
+
+	tests := []struct {
+		file     string
+		expected bool
+	}{
+		{filepath.Join(gamelistDir, GamelistFile), true},
+		{filepath.Join(gamelistDir, PegasusFile), false},
+		{filepath.Join(pegasusDir, PegasusFile), true},
+		{filepath.Join(pegasusDir, GamelistFile), false},
+	}
+	for _, tt := range tests {
+		if _, err := os.Stat(tt.file); (err == nil) != tt.expected {
+			t.Errorf("expected %s to exist: %v, got %v", tt.file, tt.expected, err)
+		}
+	}
+}
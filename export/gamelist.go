@@ -0,0 +1,238 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/anibaldeboni/screech/atomicfile"
+	"github.com/anibaldeboni/screech/scraper"
+)
+
+const GamelistFile = "gamelist.xml"
+
+// mediaFields are the gamelist fields of media files, in the order they're written
+var mediaFields = []string{"image", "thumbnail", "marquee", "video", "fanart", "manual"}
+
+// Gamelist writes the EmulationStation gamelist.xml of the system dir.
+type Gamelist struct{}
+
+// Export merges the entries into the gamelist.xml of the system dir.
+func (Gamelist) Export(system System, entries []Entry) error {
+	return writeGamelist(filepath.Join(system.Dir, GamelistFile), system.Dir, entries, fillGame)
+}
+
+// xmlNode keeps any element as is, so unknown fields and attributes of an
+// existing gamelist survive a merge.
+type xmlNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Text    string     `xml:",chardata"`
+	Nodes   []xmlNode  `xml:",any"`
+}
+
+func (n *xmlNode) child(name string) *xmlNode {
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Local == name {
+			return &n.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// setDefault sets a field only when it's missing or empty, values already in
+// the gamelist may have been edited by the user.
+func (n *xmlNode) setDefault(name, value string) {
+	if value == "" {
+		return
+	}
+	if field := n.child(name); field != nil {
+		if strings.TrimSpace(field.Text) == "" {
+			field.Text = value
+		}
+		return
+	}
+	n.Nodes = append(n.Nodes, xmlNode{XMLName: xml.Name{Local: name}, Text: value})
+}
+
+// trim drops the indentation read along with the elements.
+func (n *xmlNode) trim() {
+	if len(n.Nodes) > 0 && strings.TrimSpace(n.Text) == "" {
+		n.Text = ""
+	}
+	for i := range n.Nodes {
+		n.Nodes[i].trim()
+	}
+}
+
+// writeGamelist merges the entries into a gamelist style file, fill sets the
+// fields of a game.
+func writeGamelist(path, systemDir string, entries []Entry, fill func(game *xmlNode, systemDir string, entry Entry)) error {
+	list, err := readGamelist(path)
+	if err != nil {
+		return err
+	}
+
+	games := make(map[string]int)
+	for i, node := range list.Nodes {
+		if node.XMLName.Local != "game" {
+			continue
+		}
+		if p := node.child("path"); p != nil {
+			games[gamelistPathKey(p.Text)] = i
+		}
+	}
+
+	for _, entry := range entries {
+		romPath := relativePath(systemDir, entry.RomPath)
+		i, ok := games[gamelistPathKey(romPath)]
+		if !ok {
+			i = len(list.Nodes)
+			games[gamelistPathKey(romPath)] = i
+			list.Nodes = append(list.Nodes, xmlNode{
+				XMLName: xml.Name{Local: "game"},
+				Nodes:   []xmlNode{{XMLName: xml.Name{Local: "path"}, Text: romPath}},
+			})
+		}
+		fill(&list.Nodes[i], systemDir, entry)
+	}
+
+	return saveGamelist(path, list)
+}
+
+func fillGame(game *xmlNode, systemDir string, entry Entry) {
+	meta := entry.Game.Metadata
+
+	game.setDefault("name", entry.label())
+	game.setDefault("desc", meta.Description)
+	files := gamelistMedia(entry.Media)
+	for _, field := range mediaFields {
+		if file, ok := files[field]; ok {
+			game.setDefault(field, relativePath(systemDir, file))
+		}
+	}
+	if meta.Rating > 0 {
+		game.setDefault("rating", strconv.FormatFloat(meta.Rating, 'f', 2, 64))
+	}
+	game.setDefault("releasedate", esDate(meta.ReleaseDate))
+	game.setDefault("developer", meta.Developer)
+	game.setDefault("publisher", meta.Publisher)
+	game.setDefault("genre", meta.Genre)
+	game.setDefault("players", meta.Players)
+	if entry.Game.ID != "" {
+		if !hasAttr(game.Attrs, "id") {
+			game.Attrs = append(game.Attrs, xml.Attr{Name: xml.Name{Local: "id"}, Value: entry.Game.ID})
+		}
+		if !hasAttr(game.Attrs, "source") {
+			game.Attrs = append(game.Attrs, xml.Attr{Name: xml.Name{Local: "source"}, Value: "ScreenScraper.fr"})
+		}
+	}
+}
+
+// gamelistMedia maps the files saved for a rom to the gamelist fields: the
+// first image is the image, a second one the thumbnail.
+func gamelistMedia(files []File) map[string]string {
+	fields := make(map[string]string)
+	for _, file := range files {
+		field := mediaField(scraper.MediaType(file.Type))
+		if field == "image" && fields["image"] != "" {
+			field = "thumbnail"
+		}
+		if field != "" && fields[field] == "" {
+			fields[field] = file.Path
+		}
+	}
+	return fields
+}
+
+func mediaField(mediaType scraper.MediaType) string {
+	switch {
+	case mediaType == scraper.Manual:
+		return "manual"
+	case mediaType.Format() == scraper.VideoFormat:
+		return "video"
+	case strings.HasPrefix(string(mediaType), "wheel") || mediaType == scraper.Marquee || mediaType == scraper.MarqueeSmall:
+		return "marquee"
+	case mediaType == scraper.Fanart:
+		return "fanart"
+	case mediaType.Format() == scraper.ImageFormat:
+		return "image"
+	default:
+		return ""
+	}
+}
+
+// esDate turns a YYYY-MM-DD date into EmulationStation's format.
+func esDate(date string) string {
+	if date == "" {
+		return ""
+	}
+	parts := strings.SplitN(date, "-", 3)
+	for len(parts) < 3 {
+		parts = append(parts, "01")
+	}
+	return strings.Join(parts, "") + "T000000"
+}
+
+// relativePath returns the path relative to the system dir as frontends expect
+// it, e.g. "./Imgs/game.png", or the path itself when it's on another volume.
+func relativePath(systemDir, path string) string {
+	rel, err := filepath.Rel(systemDir, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	rel = filepath.ToSlash(rel)
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel
+}
+
+// gamelistPathKey matches the paths of a gamelist written with or without the
+// ./ prefix, as other scrapers and users do.
+func gamelistPathKey(path string) string {
+	return filepath.Clean(strings.TrimSpace(path))
+}
+
+func readGamelist(path string) (*xmlNode, error) {
+	list := &xmlNode{XMLName: xml.Name{Local: "gameList"}}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return list, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := xml.Unmarshal(data, list); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	list.trim()
+
+	return list, nil
+}
+
+func saveGamelist(path string, list *xmlNode) error {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "\t")
+	if err := enc.Encode(list); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	buf.WriteString("\n")
+
+	return atomicfile.WriteFile(path, buf.Bytes())
+}
+
+func hasAttr(attrs []xml.Attr, name string) bool {
+	return slices.ContainsFunc(attrs, func(attr xml.Attr) bool {
+		return attr.Name.Local == name
+	})
+}
@@ -0,0 +1,163 @@
+package export
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anibaldeboni/screech/scraper"
+)
+
+const existingGamelist = `<?xml version="1.0"?>
+<gameList>
+	<folder>
+		<path>./Hacks</path>
+		<name>Hacks</name>
+	</folder>
+	<game id="1" source="ScreenScraper.fr">
+		<path>./Super Mario World.sfc</path>
+		<name>My Mario</name>
+		<desc></desc>
+		<favorite>true</favorite>
+	</game>
+</gameList>
+`
+
+func TestGamelistWrite(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, GamelistFile), []byte(existingGamelist), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []Entry{{
+		RomPath: filepath.Join(dir, "Super Mario World.sfc"),
+		Game: scraper.Game{
+			ID:   "1",
+			Name: "Super Mario World",
+			Metadata: scraper.Metadata{
+				Description: "Mario is back",
+				ReleaseDate: "1990-11-21",
+				Publisher:   "Nintendo",
+				Rating:      0.9,
+			},
+		},
+		Media: []File{{Type: "box-3D", Path: filepath.Join(dir, "Imgs", "Super Mario World.png")}},
+	}, {
+		RomPath: filepath.Join(dir, "Hacks", "Tetris.gb"),
+		Game:    scraper.Game{ID: "2", Name: "Tetris", Metadata: scraper.Metadata{ReleaseDate: "1989"}},
+		Media: []File{
+			{Type: "ss", Path: filepath.Join(dir, "Imgs", "Tetris.png")},
+			{Type: "box-2D", Path: filepath.Join(dir, "Boxes", "Tetris.png")},
+			{Type: "video", Path: filepath.Join(dir, "Videos", "Tetris.mp4")},
+		},
+	}}
+	if err := (Gamelist{}).Export(System{Dir: dir}, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := readGamelist(filepath.Join(dir, GamelistFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Nodes) != 3 || list.Nodes[0].XMLName.Local != "folder" {
+		t.Fatalf("expected the folder and two games, got %+v", list.Nodes)
+	}
+
+	tests := []struct {
+		game     int
+		field    string
+		expected string
+	}{
+		{1, "name", "My Mario"},
+		{1, "favorite", "true"},
+		{1, "desc", "Mario is back"},
+		{1, "image", "./Imgs/Super Mario World.png"},
+		{1, "releasedate", "19901121T000000"},
+		{1, "publisher", "Nintendo"},
+		{1, "rating", "0.90"},
+		{2, "path", "./Hacks/Tetris.gb"},
+		{2, "name", "Tetris"},
+		{2, "image", "./Imgs/Tetris.png"},
+		{2, "thumbnail", "./Boxes/Tetris.png"},
+		{2, "video", "./Videos/Tetris.mp4"},
+		{2, "releasedate", "19890101T000000"},
+	}
+	for _, tt := range tests {
+		field := list.Nodes[tt.game].child(tt.field)
+		if field == nil || field.Text != tt.expected {
+			t.Errorf("expected %s of game %d to be %q, got %+v", tt.field, tt.game, tt.expected, field)
+		}
+	}
+
+	if attrs := list.Nodes[1].Attrs; len(attrs) != 2 {
+		t.Errorf("expected the existing attributes to be kept, got %+v", attrs)
+	}
+	if attrs := list.Nodes[2].Attrs; len(attrs) != 2 || attrs[0] != (xml.Attr{Name: xml.Name{Local: "id"}, Value: "2"}) {
+		t.Errorf("expected the game id attribute, got %+v", attrs)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, GamelistFile))
+	if !strings.HasPrefix(string(data), xml.Header+"<gameList>\n\t<folder>\n\t\t<path>./Hacks</path>") {
+		t.Errorf("unexpected gamelist layout:\n%s", data)
+	}
+}
+
+func TestGamelistWriteInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, GamelistFile)
+	_ = os.WriteFile(path, []byte("<gameList><game>"), 0644)
+
+	if err := (Gamelist{}).Export(System{Dir: dir}, []Entry{{RomPath: filepath.Join(dir, "game.sfc")}}); err == nil {
+		t.Error("expected an error for an invalid gamelist")
+	}
+
+	// A gamelist that can't be parsed is never overwritten
+	if data, _ := os.ReadFile(path); string(data) != "<gameList><game>" {
+		t.Errorf("expected the gamelist to be left alone, got %s", data)
+	}
+}
+
+func TestGamelistWritePathWithoutPrefix(t *testing.T) {
+	dir := t.TempDir()
+	existing := `<?xml version="1.0"?>
+<gameList>
+	<game>
+		<path>Hacks/Tetris.gb</path>
+		<name>My Tetris</name>
+	</game>
+</gameList>
+`
+	if err := os.WriteFile(filepath.Join(dir, GamelistFile), []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []Entry{{
+		RomPath: filepath.Join(dir, "Hacks", "Tetris.gb"),
+		Game:    scraper.Game{ID: "2", Name: "Tetris", Metadata: scraper.Metadata{Publisher: "Nintendo"}},
+	}}
+	if err := (Gamelist{}).Export(System{Dir: dir}, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := readGamelist(filepath.Join(dir, GamelistFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var games []xmlNode
+	for _, node := range list.Nodes {
+		if node.XMLName.Local == "game" {
+			games = append(games, node)
+		}
+	}
+	if len(games) != 1 {
+		t.Fatalf("expected the existing game to be updated, got %+v", games)
+	}
+	if name := games[0].child("name"); name == nil || name.Text != "My Tetris" {
+		t.Errorf("expected the existing name to be kept, got %+v", name)
+	}
+	if publisher := games[0].child("publisher"); publisher == nil || publisher.Text != "Nintendo" {
+		t.Errorf("expected the publisher to be added, got %+v", publisher)
+	}
+}
@@ -0,0 +1,45 @@
+package export
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/anibaldeboni/screech/scraper"
+)
+
+const MiyooGamelistFile = "miyoogamelist.xml"
+
+// Miyoo writes the miyoogamelist.xml of the system dir, the list Miyoo and
+// CrossMix style frontends show the game names and images from.
+type Miyoo struct{}
+
+func (Miyoo) Export(system System, entries []Entry) error {
+	return writeGamelist(filepath.Join(system.Dir, MiyooGamelistFile), system.Dir, entries, fillMiyooGame)
+}
+
+// fillMiyooGame sets the name in the preferred region and the first image,
+// the only fields these frontends read.
+func fillMiyooGame(game *xmlNode, systemDir string, entry Entry) {
+	name := entry.Game.Metadata.RegionName
+	if name == "" {
+		name = entry.label()
+	}
+	// File names and default names, e.g. listed by the frontend itself, are
+	// what this list fixes, they're not kept like the names the user edited
+	if field := game.child("name"); field != nil && isDefaultName(strings.TrimSpace(field.Text), entry) {
+		field.Text = name
+	}
+	game.setDefault("name", name)
+
+	for _, file := range entry.Media {
+		if scraper.MediaType(file.Type).Format() == scraper.ImageFormat {
+			game.setDefault("image", relativePath(systemDir, file.Path))
+			break
+		}
+	}
+}
+
+func isDefaultName(name string, entry Entry) bool {
+	fileName := filepath.Base(entry.RomPath)
+	return name == fileName || name == strings.TrimSuffix(fileName, filepath.Ext(fileName)) || name == entry.Game.Name
+}
@@ -0,0 +1,82 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anibaldeboni/screech/scraper"
+)
+
+func TestMiyooWrite(t *testing.T) {
+	dir := t.TempDir()
+	existing := `<?xml version="1.0"?>
+<gameList>
+	<game>
+		<path>./Tetris.gb</path>
+		<name>My Tetris</name>
+	</game>
+	<game>
+		<path>./Super Mario World (USA) [!].sfc</path>
+		<name>Super Mario World (USA) [!]</name>
+	</game>
+</gameList>
+`
+	if err := os.WriteFile(filepath.Join(dir, MiyooGamelistFile), []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []Entry{{
+		RomPath: filepath.Join(dir, "Super Mario World (USA) [!].sfc"),
+		Game: scraper.Game{
+			Name:     "Super Mario World",
+			Metadata: scraper.Metadata{RegionName: "Super Mario World (US)", Description: "Mario is back"},
+		},
+		Media: []File{
+			{Type: "video", Path: filepath.Join(dir, "Videos", "Super Mario World (USA) [!].mp4")},
+			{Type: "box-2D", Path: filepath.Join(dir, "Imgs", "Super Mario World (USA) [!].png")},
+		},
+	}, {
+		RomPath: filepath.Join(dir, "Tetris.gb"),
+		Game:    scraper.Game{Name: "Tetris"},
+		Media:   []File{{Type: "ss", Path: filepath.Join(dir, "Imgs", "Tetris.png")}},
+	}, {
+		RomPath: filepath.Join(dir, "Unknown (Japan).gb"),
+	}}
+	if err := (Miyoo{}).Export(System{Dir: dir}, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := readGamelist(filepath.Join(dir, MiyooGamelistFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Nodes) != 3 {
+		t.Fatalf("expected three games, got %+v", list.Nodes)
+	}
+
+	tests := []struct {
+		game     int
+		field    string
+		expected string
+	}{
+		{0, "name", "My Tetris"},
+		{0, "image", "./Imgs/Tetris.png"},
+		{1, "path", "./Super Mario World (USA) [!].sfc"},
+		{1, "name", "Super Mario World (US)"},
+		{1, "image", "./Imgs/Super Mario World (USA) [!].png"},
+		{2, "name", "Unknown (Japan)"},
+	}
+	for _, tt := range tests {
+		field := list.Nodes[tt.game].child(tt.field)
+		if field == nil || field.Text != tt.expected {
+			t.Errorf("expected %s of game %d to be %q, got %+v", tt.field, tt.game, tt.expected, field)
+		}
+	}
+
+	for _, field := range []string{"desc", "video"} {
+		if list.Nodes[1].child(field) != nil {
+			t.Errorf("expected no %s field", field)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/anibaldeboni/screech/atomicfile"
+	"github.com/anibaldeboni/screech/scraper"
+)
+
+const PegasusFile = "metadata.pegasus.txt"
+
+// Pegasus writes the metadata.pegasus.txt of the system dir.
+type Pegasus struct{}
+
+// pegasusAssets are the asset keys of media types, the first file of a key wins
+var pegasusAssets = map[scraper.MediaType]string{
+	scraper.Box2D:          "box_front",
+	scraper.Box3D:          "box_front",
+	scraper.MixV1:          "box_front",
+	scraper.MixV2:          "box_front",
+	scraper.LocalMix:       "box_front",
+	scraper.Box2DBack:      "box_back",
+	scraper.Box2DSide:      "box_spine",
+	scraper.Screenshot:     "screenshot",
+	scraper.TitleScreen:    "titlescreen",
+	scraper.Wheel:          "logo",
+	scraper.WheelHD:        "logo",
+	scraper.WheelCarbon:    "logo",
+	scraper.WheelSteel:     "logo",
+	scraper.Marquee:        "marquee",
+	scraper.MarqueeSmall:   "marquee",
+	scraper.Fanart:         "background",
+	scraper.Support2D:      "cartridge",
+	scraper.SupportTexture: "cartridge",
+	scraper.Video:          "video",
+	scraper.VideoNorm:      "video",
+}
+
+// pegasusBlock is a collection or game of a metadata file, kept as the lines
+// read so comments and unknown keys survive a merge.
+type pegasusBlock struct {
+	lines []string
+}
+
+// keys returns the keys set in the block, lower cased.
+func (b *pegasusBlock) keys() map[string]string {
+	keys := make(map[string]string)
+	for _, line := range b.lines {
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			keys[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+		}
+	}
+	return keys
+}
+
+// files returns the rom paths of a game block, from its file or files keys.
+func (b *pegasusBlock) files() []string {
+	var files []string
+	inFiles := false
+	for _, line := range b.lines {
+		if inFiles && line != "" && (line[0] == ' ' || line[0] == '\t') {
+			files = append(files, cleanPegasusPath(line))
+			continue
+		}
+		inFiles = false
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "file":
+			files = append(files, cleanPegasusPath(value))
+		case "files":
+			inFiles = true
+			if value = strings.TrimSpace(value); value != "" {
+				files = append(files, cleanPegasusPath(value))
+			}
+		}
+	}
+	return files
+}
+
+// set adds the key when the block doesn't have it, values already there may
+// have been edited by the user.
+func (b *pegasusBlock) set(keys map[string]string, key, value string) {
+	if value == "" {
+		return
+	}
+	if _, ok := keys[key]; ok {
+		return
+	}
+	keys[key] = value
+
+	// New keys go before the blank lines separating the blocks
+	end := len(b.lines)
+	for end > 0 && strings.TrimSpace(b.lines[end-1]) == "" {
+		end--
+	}
+	lines := append([]string{}, b.lines[:end]...)
+	lines = append(lines, pegasusField(key, value)...)
+	b.lines = append(lines, b.lines[end:]...)
+}
+
+func (Pegasus) Export(system System, entries []Entry) error {
+	path := filepath.Join(system.Dir, PegasusFile)
+
+	blocks, err := readPegasus(path)
+	if err != nil {
+		return err
+	}
+	if len(blocks) == 0 {
+		blocks = append(blocks, &pegasusBlock{lines: []string{"collection: " + system.Name, ""}})
+	}
+
+	games := make(map[string]*pegasusBlock)
+	for _, block := range blocks {
+		if _, ok := block.keys()["game"]; !ok {
+			continue
+		}
+		for _, file := range block.files() {
+			games[file] = block
+		}
+	}
+
+	for _, entry := range entries {
+		romPath := cleanPegasusPath(relativePath(system.Dir, entry.RomPath))
+		block, ok := games[romPath]
+		if !ok {
+			block = &pegasusBlock{lines: []string{"game: " + entry.label(), "file: " + romPath, ""}}
+			games[romPath] = block
+			blocks = append(blocks, block)
+		}
+		fillPegasusGame(block, system.Dir, entry)
+	}
+
+	var buf bytes.Buffer
+	for _, block := range blocks {
+		for _, line := range block.lines {
+			buf.WriteString(line)
+			buf.WriteString("\n")
+		}
+	}
+
+	return atomicfile.WriteFile(path, buf.Bytes())
+}
+
+func fillPegasusGame(block *pegasusBlock, systemDir string, entry Entry) {
+	meta := entry.Game.Metadata
+	keys := block.keys()
+
+	block.set(keys, "developer", meta.Developer)
+	block.set(keys, "publisher", meta.Publisher)
+	block.set(keys, "genre", meta.Genre)
+	block.set(keys, "players", meta.Players)
+	block.set(keys, "release", meta.ReleaseDate)
+	if meta.Rating > 0 {
+		block.set(keys, "rating", strconv.Itoa(int(meta.Rating*100+0.5))+"%")
+	}
+	block.set(keys, "description", meta.Description)
+	for _, file := range entry.Media {
+		if asset, ok := pegasusAssets[scraper.MediaType(file.Type)]; ok {
+			block.set(keys, "assets."+asset, relativePath(systemDir, file.Path))
+		}
+	}
+}
+
+// pegasusField formats a key, indenting the lines of multi line values and
+// marking their empty lines with a dot as Pegasus expects.
+func pegasusField(key, value string) []string {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(value, "\r\n", "\n")), "\n")
+	field := []string{key + ": " + strings.TrimSpace(lines[0])}
+	for _, line := range lines[1:] {
+		if line = strings.TrimSpace(line); line == "" {
+			line = "."
+		}
+		field = append(field, "  "+line)
+	}
+	return field
+}
+
+func cleanPegasusPath(path string) string {
+	return strings.TrimPrefix(strings.TrimSpace(path), "./")
+}
+
+// readPegasus splits a metadata file into its blocks, the lines before the
+// first block, like comments, go with the first one.
+func readPegasus(path string) ([]*pegasusBlock, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var (
+		blocks  []*pegasusBlock
+		current = &pegasusBlock{}
+	)
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		line = strings.TrimRight(line, "\r")
+		lower := strings.ToLower(line)
+		if (strings.HasPrefix(lower, "game:") || strings.HasPrefix(lower, "collection:")) && len(current.keys()) > 0 {
+			blocks = append(blocks, current)
+			current = &pegasusBlock{}
+		}
+		current.lines = append(current.lines, line)
+	}
+	blocks = append(blocks, current)
+
+	// Keep a blank line between the last block and new ones
+	if last := blocks[len(blocks)-1]; strings.TrimSpace(last.lines[len(last.lines)-1]) != "" {
+		last.lines = append(last.lines, "")
+	}
+
+	return blocks, nil
+}
@@ -0,0 +1,96 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anibaldeboni/screech/scraper"
+)
+
+func TestPegasusExport(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, PegasusFile)
+	_ = os.WriteFile(path, []byte(`# My collection
+collection: SNES
+extensions: sfc, smc
+
+game: My Mario
+file: Super Mario World.sfc
+developer: Me
+x-favorite: yes
+`), 0644)
+
+	entries := []Entry{
+		{
+			RomPath: filepath.Join(dir, "Super Mario World.sfc"),
+			Game: scraper.Game{Name: "Super Mario World", Metadata: scraper.Metadata{
+				Developer: "Nintendo EAD",
+				Publisher: "Nintendo",
+				Rating:    0.9,
+			}},
+			Media: []File{{Type: "box-3D", Path: filepath.Join(dir, "Imgs", "Super Mario World.png")}},
+		},
+		{
+			RomPath: filepath.Join(dir, "Hacks", "Tetris.gb"),
+			Game: scraper.Game{Name: "Tetris", Metadata: scraper.Metadata{
+				ReleaseDate: "1989-06-14",
+				Description: "Falling blocks.\n\nLine them up.",
+			}},
+			Media: []File{
+				{Type: "wheel", Path: filepath.Join(dir, "Wheels", "Tetris.png")},
+				{Type: "video", Path: filepath.Join(dir, "Videos", "Tetris.mp4")},
+			},
+		},
+	}
+	if err := (Pegasus{}).Export(System{Dir: dir, Name: "SNES"}, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# My collection
+collection: SNES
+extensions: sfc, smc
+
+game: My Mario
+file: Super Mario World.sfc
+developer: Me
+x-favorite: yes
+publisher: Nintendo
+rating: 90%
+assets.box_front: ./Imgs/Super Mario World.png
+
+game: Tetris
+file: Hacks/Tetris.gb
+release: 1989-06-14
+description: Falling blocks.
+  .
+  Line them up.
+assets.logo: ./Wheels/Tetris.png
+assets.video: ./Videos/Tetris.mp4
+
+`
+	if data, _ := os.ReadFile(path); string(data) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, data)
+	}
+
+	// Exporting again changes nothing
+	if err := (Pegasus{}).Export(System{Dir: dir, Name: "SNES"}, entries); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != expected {
+		t.Errorf("expected a second export to keep the file, got:\n%s", data)
+	}
+}
+
+func TestPegasusExportNewFile(t *testing.T) {
+	dir := t.TempDir()
+	entries := []Entry{{RomPath: filepath.Join(dir, "Tetris.gb")}}
+	if err := (Pegasus{}).Export(System{Dir: dir, Name: "Game Boy"}, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "collection: Game Boy\n\ngame: Tetris\nfile: Tetris.gb\n\n"
+	if data, _ := os.ReadFile(filepath.Join(dir, PegasusFile)); string(data) != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, data)
+	}
+}
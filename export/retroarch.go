@@ -0,0 +1,162 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anibaldeboni/screech/atomicfile"
+	"github.com/anibaldeboni/screech/config"
+	"github.com/anibaldeboni/screech/scraper"
+)
+
+var (
+	NoPlaylistsDirErr = errors.New("retroarch.playlists is not set")
+	NoPlaylistNameErr = errors.New("the system has no playlist name, set it to the libretro database name")
+
+	// retroarchThumbnails are the thumbnail dirs media types are copied to
+	retroarchThumbnails = map[scraper.MediaType]string{
+		scraper.Box2D:       "Named_Boxarts",
+		scraper.Box3D:       "Named_Boxarts",
+		scraper.MixV1:       "Named_Boxarts",
+		scraper.MixV2:       "Named_Boxarts",
+		scraper.LocalMix:    "Named_Boxarts",
+		scraper.Screenshot:  "Named_Snaps",
+		scraper.TitleScreen: "Named_Titles",
+	}
+)
+
+// RetroArch writes the .lpl playlist of the system to the RetroArch playlists
+// dir and, when the thumbnails dir is set, copies the images there named after
+// the playlist labels as RetroArch looks them up.
+type RetroArch struct{}
+
+func (RetroArch) Export(system System, entries []Entry) error {
+	if config.RetroArch.Playlists == "" {
+		return NoPlaylistsDirErr
+	}
+	if system.Playlist == "" {
+		return NoPlaylistNameErr
+	}
+
+	path := filepath.Join(config.RetroArch.Playlists, system.Playlist+".lpl")
+	playlist, err := readPlaylist(path)
+	if err != nil {
+		return err
+	}
+
+	items, _ := playlist["items"].([]any)
+	indexes := make(map[string]int, len(items))
+	for i, item := range items {
+		if fields, ok := item.(map[string]any); ok {
+			if romPath, ok := fields["path"].(string); ok {
+				indexes[romPath] = i
+			}
+		}
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		i, ok := indexes[entry.RomPath]
+		if !ok {
+			i = len(items)
+			indexes[entry.RomPath] = i
+			items = append(items, map[string]any{
+				"path":      entry.RomPath,
+				"core_path": "DETECT",
+				"core_name": "DETECT",
+				"crc32":     "DETECT",
+			})
+		}
+
+		fields, ok := items[i].(map[string]any)
+		if !ok {
+			continue
+		}
+		setDefault(fields, "label", entry.label())
+		setDefault(fields, "db_name", system.Playlist+".lpl")
+
+		label, _ := fields["label"].(string)
+		if err := copyThumbnails(system.Playlist, label, entry.Media); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	playlist["items"] = items
+
+	data, err := json.MarshalIndent(playlist, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	if err := atomicfile.WriteFile(path, append(data, '\n')); err != nil {
+		return err
+	}
+
+	return errors.Join(errs...)
+}
+
+// setDefault sets a field only when it's missing or empty.
+func setDefault(fields map[string]any, key, value string) {
+	if current, _ := fields[key].(string); current == "" {
+		fields[key] = value
+	}
+}
+
+func readPlaylist(path string) (map[string]any, error) {
+	playlist := map[string]any{
+		"version":              "1.5",
+		"default_core_path":    "",
+		"default_core_name":    "",
+		"label_display_mode":   0,
+		"right_thumbnail_mode": 0,
+		"left_thumbnail_mode":  0,
+		"sort_mode":            0,
+		"items":                []any{},
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return playlist, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, &playlist); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return playlist, nil
+}
+
+// copyThumbnails copies the PNG images of an entry to the RetroArch thumbnails
+// dir, thumbnails already there are kept.
+func copyThumbnails(playlist, label string, files []File) error {
+	if config.RetroArch.Thumbnails == "" || label == "" {
+		return nil
+	}
+
+	for _, file := range files {
+		dir, ok := retroarchThumbnails[scraper.MediaType(file.Type)]
+		if !ok || !strings.EqualFold(filepath.Ext(file.Path), ".png") {
+			continue
+		}
+
+		dest := filepath.Join(config.RetroArch.Thumbnails, playlist, dir, scraper.LibretroThumbnailName(label)+".png")
+		if _, err := os.Stat(dest); err == nil {
+			continue
+		}
+
+		data, err := os.ReadFile(file.Path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file.Path, err)
+		}
+		if err := atomicfile.WriteFile(dest, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
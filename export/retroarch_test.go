@@ -0,0 +1,91 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anibaldeboni/screech/config"
+	"github.com/anibaldeboni/screech/scraper"
+)
+
+func TestRetroArchExport(t *testing.T) {
+	dir := t.TempDir()
+	originalRetroArch := config.RetroArch
+	defer func() { config.RetroArch = originalRetroArch }()
+	config.RetroArch.Playlists = filepath.Join(dir, "playlists")
+	config.RetroArch.Thumbnails = filepath.Join(dir, "thumbnails")
+
+	const name = "Nintendo - Super Nintendo Entertainment System"
+	_ = os.MkdirAll(config.RetroArch.Playlists, 0755)
+	_ = os.WriteFile(filepath.Join(config.RetroArch.Playlists, name+".lpl"), []byte(`{
+  "version": "1.5",
+  "default_core_path": "/cores/snes9x_libretro.so",
+  "items": [
+    {"path": "/roms/SFC/Super Mario World.sfc", "label": "My Mario", "core_path": "/cores/snes9x_libretro.so", "core_name": "Snes9x", "crc32": "DETECT"}
+  ]
+}`), 0644)
+
+	image := filepath.Join(dir, "Imgs", "Zelda.png")
+	_ = os.MkdirAll(filepath.Dir(image), 0755)
+	_ = os.WriteFile(image, []byte("png"), 0644)
+
+	entries := []Entry{
+		{RomPath: "/roms/SFC/Super Mario World.sfc", Game: scraper.Game{Name: "Super Mario World"}},
+		{
+			RomPath: "/roms/SFC/Zelda.sfc",
+			Game:    scraper.Game{Name: "The Legend of Zelda: A Link to the Past"},
+			Media: []File{
+				{Type: "box-2D", Path: image},
+				{Type: "video", Path: filepath.Join(dir, "Videos", "Zelda.mp4")},
+			},
+		},
+	}
+	if err := (RetroArch{}).Export(System{Name: "SNES", Playlist: name}, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(config.RetroArch.Playlists, name+".lpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var playlist struct {
+		DefaultCorePath string              `json:"default_core_path"`
+		Items           []map[string]string `json:"items"`
+	}
+	if err := json.Unmarshal(data, &playlist); err != nil {
+		t.Fatal(err)
+	}
+
+	if playlist.DefaultCorePath != "/cores/snes9x_libretro.so" || len(playlist.Items) != 2 {
+		t.Fatalf("unexpected playlist %s", data)
+	}
+	if item := playlist.Items[0]; item["label"] != "My Mario" || item["core_name"] != "Snes9x" || item["db_name"] != name+".lpl" {
+		t.Errorf("expected the existing item to be kept, got %v", item)
+	}
+	if item := playlist.Items[1]; item["label"] != "The Legend of Zelda: A Link to the Past" || item["path"] != "/roms/SFC/Zelda.sfc" || item["core_path"] != "DETECT" {
+		t.Errorf("unexpected new item %v", item)
+	}
+
+	thumbnail := filepath.Join(config.RetroArch.Thumbnails, name, "Named_Boxarts", "The Legend of Zelda_ A Link to the Past.png")
+	if _, err := os.Stat(thumbnail); err != nil {
+		t.Errorf("expected the boxart to be copied as %s: %v", thumbnail, err)
+	}
+}
+
+func TestRetroArchExportSettings(t *testing.T) {
+	originalRetroArch := config.RetroArch
+	defer func() { config.RetroArch = originalRetroArch }()
+
+	config.RetroArch.Playlists = ""
+	if err := (RetroArch{}).Export(System{Playlist: "Sega - Mega Drive - Genesis"}, nil); !errors.Is(err, NoPlaylistsDirErr) {
+		t.Errorf("expected NoPlaylistsDirErr, got %v", err)
+	}
+
+	config.RetroArch.Playlists = t.TempDir()
+	if err := (RetroArch{}).Export(System{}, nil); !errors.Is(err, NoPlaylistNameErr) {
+		t.Errorf("expected NoPlaylistNameErr, got %v", err)
+	}
+}
@@ -5,3 +5,5 @@ go 1.22.6
 require github.com/veandco/go-sdl2 v0.4.40
 
 require gopkg.in/yaml.v3 v3.0.1
+
+require golang.org/x/image v0.18.0
@@ -0,0 +1,163 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+
+	"golang.org/x/image/draw"
+	yaml "gopkg.in/yaml.v3"
+)
+
+var NoLayersErr = errors.New("no layer available for the mix")
+
+// Layout is a mix template. Positions and sizes are fractions of the canvas so
+// the same template works for any thumbnail size.
+type Layout struct {
+	Layers []Layer `yaml:"layers"`
+}
+
+// Layer places one media of the game on the mix, in template order, the first
+// layer at the bottom.
+type Layer struct {
+	Type string `yaml:"type"`
+	// X and Y are the top-left corner of the layer box
+	X float64 `yaml:"x"`
+	Y float64 `yaml:"y"`
+	// Width and Height are the size of the box, the media is scaled to fit it
+	// keeping its aspect ratio and centered in it
+	Width    float64 `yaml:"width"`
+	Height   float64 `yaml:"height"`
+	Required bool    `yaml:"required,omitempty"`
+	Shadow   *Shadow `yaml:"shadow,omitempty"`
+}
+
+// Shadow is a drop shadow drawn under a layer. Offsets and blur are in pixels.
+type Shadow struct {
+	OffsetX int     `yaml:"offset-x"`
+	OffsetY int     `yaml:"offset-y"`
+	Blur    int     `yaml:"blur"`
+	Opacity float64 `yaml:"opacity"`
+}
+
+// DefaultLayout resembles the ScreenScraper mixrbv2 style: the screenshot as
+// background, the 3D box in the bottom left corner, the cartridge and the
+// wheel on the bottom right.
+var DefaultLayout = Layout{
+	Layers: []Layer{
+		{Type: "ss", X: 0.1, Y: 0, Width: 0.9, Height: 0.8, Required: true},
+		{Type: "box-3D", X: 0, Y: 0.4, Width: 0.45, Height: 0.6, Shadow: &Shadow{OffsetX: 6, OffsetY: 6, Blur: 6, Opacity: 0.6}},
+		{Type: "support-2D", X: 0.4, Y: 0.6, Width: 0.25, Height: 0.4, Shadow: &Shadow{OffsetX: 4, OffsetY: 4, Blur: 4, Opacity: 0.6}},
+		{Type: "wheel", X: 0.6, Y: 0.7, Width: 0.4, Height: 0.3, Shadow: &Shadow{OffsetX: 4, OffsetY: 4, Blur: 4, Opacity: 0.6}},
+	},
+}
+
+// LoadLayout reads a mix template from a YAML file.
+func LoadLayout(path string) (Layout, error) {
+	var layout Layout
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return layout, fmt.Errorf("failed to read mix layout: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &layout); err != nil {
+		return layout, fmt.Errorf("failed to parse mix layout %s: %w", path, err)
+	}
+	if len(layout.Layers) == 0 {
+		return layout, fmt.Errorf("mix layout %s has no layers", path)
+	}
+
+	return layout, nil
+}
+
+// Compose draws the layers found in images, by media type, on a transparent
+// canvas of the given size. Missing layers are left out unless required.
+func Compose(layout Layout, images map[string]image.Image, width, height int) (image.Image, error) {
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	var drawn int
+	for _, layer := range layout.Layers {
+		img, ok := images[layer.Type]
+		if !ok {
+			if layer.Required {
+				return nil, fmt.Errorf("missing %s layer for the mix", layer.Type)
+			}
+			continue
+		}
+
+		box := image.Rect(
+			int(layer.X*float64(width)),
+			int(layer.Y*float64(height)),
+			int((layer.X+layer.Width)*float64(width)),
+			int((layer.Y+layer.Height)*float64(height)),
+		)
+		if box.Empty() {
+			continue
+		}
+
+		scaled := fit(img, box.Dx(), box.Dy())
+		b := scaled.Bounds()
+		at := box.Min.Add(image.Pt((box.Dx()-b.Dx())/2, (box.Dy()-b.Dy())/2))
+
+		if layer.Shadow != nil {
+			drawShadow(canvas, scaled, at, *layer.Shadow)
+		}
+		draw.Draw(canvas, b.Add(at), scaled, b.Min, draw.Over)
+		drawn++
+	}
+
+	if drawn == 0 {
+		return nil, NoLayersErr
+	}
+
+	return canvas, nil
+}
+
+// drawShadow draws the blurred silhouette of img, offset from where the image
+// itself is drawn.
+func drawShadow(canvas *image.RGBA, img image.Image, at image.Point, shadow Shadow) {
+	b := img.Bounds()
+	margin := shadow.Blur
+	mask := image.NewAlpha(image.Rect(0, 0, b.Dx()+2*margin, b.Dy()+2*margin))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			_, _, _, a := img.At(x, y).RGBA()
+			mask.SetAlpha(x-b.Min.X+margin, y-b.Min.Y+margin, color.Alpha{A: uint8(float64(a>>8) * shadow.Opacity)})
+		}
+	}
+	blur(mask, shadow.Blur)
+
+	dest := mask.Bounds().Add(at).Add(image.Pt(shadow.OffsetX-margin, shadow.OffsetY-margin))
+	draw.DrawMask(canvas, dest, image.Black, image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// blur softens the mask with a horizontal and a vertical box blur.
+func blur(mask *image.Alpha, radius int) {
+	if radius <= 0 {
+		return
+	}
+
+	b := mask.Bounds()
+	tmp := make([]uint8, len(mask.Pix))
+	boxBlur(mask.Pix, tmp, b.Dx(), b.Dy(), 1, mask.Stride, radius)
+	boxBlur(tmp, mask.Pix, b.Dy(), b.Dx(), mask.Stride, 1, radius)
+}
+
+// boxBlur averages each pixel of the lines with its neighbours within radius.
+// step is the distance between two pixels of a line and lineStep between the
+// start of two lines.
+func boxBlur(src, dst []uint8, length, lines, step, lineStep, radius int) {
+	for line := range lines {
+		start := line * lineStep
+		for i := range length {
+			var sum, count int
+			for j := max(i-radius, 0); j <= min(i+radius, length-1); j++ {
+				sum += int(src[start+j*step])
+				count++
+			}
+			dst[start+i*step] = uint8(sum / count)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package imaging
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func solidImage(width, height int, c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: c}, image.Point{}, draw.Src)
+	return img
+}
+
+func TestCompose(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	layout := Layout{Layers: []Layer{
+		{Type: "ss", X: 0, Y: 0, Width: 1, Height: 1, Required: true},
+		{Type: "box-3D", X: 0, Y: 0.5, Width: 0.5, Height: 0.5, Shadow: &Shadow{OffsetX: 10, OffsetY: 0, Opacity: 1}},
+		{Type: "wheel", X: 0.5, Y: 0, Width: 0.5, Height: 0.5},
+	}}
+
+	img, err := Compose(layout, map[string]image.Image{
+		"ss":     solidImage(40, 20, red),
+		"box-3D": solidImage(10, 10, blue),
+	}, 200, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		x, y     int
+		expected color.RGBA
+	}{
+		{"background", 150, 25, red},
+		{"box", 50, 75, blue},
+		{"shadow", 80, 75, color.RGBA{A: 255}},
+		{"past the shadow", 90, 75, red},
+	}
+	for _, tt := range tests {
+		if got := color.RGBAModel.Convert(img.At(tt.x, tt.y)).(color.RGBA); got != tt.expected {
+			t.Errorf("%s: expected %v at %d,%d, got %v", tt.name, tt.expected, tt.x, tt.y, got)
+		}
+	}
+	if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 100 {
+		t.Errorf("expected a 200x100 mix, got %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+func TestComposeMissingLayers(t *testing.T) {
+	layout := Layout{Layers: []Layer{
+		{Type: "ss", Width: 1, Height: 1, Required: true},
+		{Type: "wheel", Width: 1, Height: 1},
+	}}
+
+	if _, err := Compose(layout, map[string]image.Image{"wheel": solidImage(1, 1, color.White)}, 10, 10); err == nil {
+		t.Error("expected an error for a missing required layer")
+	}
+
+	layout.Layers[0].Required = false
+	if _, err := Compose(layout, map[string]image.Image{}, 10, 10); !errors.Is(err, NoLayersErr) {
+		t.Errorf("expected NoLayersErr, got %v", err)
+	}
+}
+
+func TestBlur(t *testing.T) {
+	mask := image.NewAlpha(image.Rect(0, 0, 5, 5))
+	mask.SetAlpha(2, 2, color.Alpha{A: 255})
+	blur(mask, 1)
+
+	if a := mask.AlphaAt(2, 2).A; a != 255/9 {
+		t.Errorf("expected the center to be averaged to %d, got %d", 255/9, a)
+	}
+	if a := mask.AlphaAt(1, 1).A; a != 255/9 {
+		t.Errorf("expected the corner neighbour to be averaged to %d, got %d", 255/9, a)
+	}
+	if a := mask.AlphaAt(0, 0).A; a != 0 {
+		t.Errorf("expected pixels out of the radius to stay clear, got %d", a)
+	}
+}
+
+func TestLoadLayout(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mix.yaml")
+	_ = os.WriteFile(path, []byte(`
+layers:
+  - type: ss
+    x: 0.1
+    width: 0.9
+    height: 0.8
+    required: true
+  - type: box-3D
+    y: 0.4
+    width: 0.45
+    height: 0.6
+    shadow:
+      offset-x: 6
+      offset-y: 6
+      blur: 6
+      opacity: 0.6
+`), 0644)
+
+	layout, err := LoadLayout(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layout.Layers) != 2 || !layout.Layers[0].Required || layout.Layers[0].X != 0.1 {
+		t.Errorf("unexpected layout %+v", layout)
+	}
+	if shadow := layout.Layers[1].Shadow; shadow == nil || shadow.Blur != 6 || shadow.Opacity != 0.6 {
+		t.Errorf("unexpected shadow %+v", shadow)
+	}
+
+	_ = os.WriteFile(path, []byte("layers: []"), 0644)
+	if _, err := LoadLayout(path); err == nil {
+		t.Error("expected an error for a layout without layers")
+	}
+}
@@ -0,0 +1,214 @@
+// Package imaging post-processes downloaded art so it always has the size and
+// format the frontend expects, whatever the server returned.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+
+	"github.com/anibaldeboni/screech/atomicfile"
+)
+
+type Mode string
+
+const (
+	// Fit scales the image to fit inside the box, keeping its aspect ratio.
+	Fit Mode = "fit"
+	// Fill scales the image to cover the box and crops what overflows.
+	Fill Mode = "fill"
+
+	PNG  = "png"
+	JPEG = "jpeg"
+
+	DefaultQuality = 90
+)
+
+var (
+	UnknownModeErr   = errors.New("unknown resize mode, choose between fit and fill")
+	UnknownFormatErr = errors.New("unknown image format, choose between png and jpeg")
+)
+
+// Options tells how an image is processed. Zero values leave that step out:
+// no resize mode keeps the size, no format keeps the downloaded one.
+type Options struct {
+	Width   int
+	Height  int
+	Resize  Mode
+	Pad     bool
+	Format  string
+	Quality int
+}
+
+// Enabled tells whether the options change the image at all.
+func (o Options) Enabled() bool {
+	return o.Resize != "" || o.Pad || o.Format != ""
+}
+
+// Extension returns the extension of the configured format, empty when the
+// downloaded format is kept.
+func (o Options) Extension() string {
+	switch o.Format {
+	case PNG:
+		return ".png"
+	case JPEG, "jpg":
+		return ".jpg"
+	default:
+		return ""
+	}
+}
+
+// OutputExtension returns the extension of an image of extension ext once
+// processed. WebP can't be encoded, so when the format is kept it's saved as
+// PNG.
+func (o Options) OutputExtension(ext string) string {
+	if !o.Enabled() {
+		return ext
+	}
+	if configured := o.Extension(); configured != "" {
+		return configured
+	}
+	if strings.EqualFold(ext, ".webp") {
+		return ".png"
+	}
+	return ext
+}
+
+func (o Options) validate() error {
+	switch o.Resize {
+	case "", Fit, Fill:
+	default:
+		return fmt.Errorf("%w: %s", UnknownModeErr, o.Resize)
+	}
+	switch o.Format {
+	case "", PNG, JPEG, "jpg":
+	default:
+		return fmt.Errorf("%w: %s", UnknownFormatErr, o.Format)
+	}
+	return nil
+}
+
+// ProcessFile processes the image at path in place. The processed image is
+// written next to it and renamed over it, so path is never left half written.
+func ProcessFile(path string, o Options) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read image: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := Process(bytes.NewReader(data), &out, o); err != nil {
+		return err
+	}
+
+	if err := atomicfile.WriteFile(path, out.Bytes()); err != nil {
+		return fmt.Errorf("failed to write image: %w", err)
+	}
+	return nil
+}
+
+// Process decodes an image, resizes and pads it to the configured box and
+// encodes it to the configured format.
+func Process(r io.Reader, w io.Writer, o Options) error {
+	if err := o.validate(); err != nil {
+		return err
+	}
+
+	img, format, err := image.Decode(r)
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if o.Width > 0 && o.Height > 0 {
+		switch o.Resize {
+		case Fit:
+			img = fit(img, o.Width, o.Height)
+		case Fill:
+			img = fill(img, o.Width, o.Height)
+		}
+		if o.Pad {
+			img = pad(img, o.Width, o.Height)
+		}
+	}
+
+	if o.Format != "" {
+		format = o.Format
+	}
+
+	return encode(w, img, format, o.Quality)
+}
+
+// Scale resizes the image to exactly width by height.
+func Scale(img image.Image, width, height int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, max(width, 1), max(height, 1)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Over, nil)
+	return dst
+}
+
+func fit(img image.Image, width, height int) image.Image {
+	b := img.Bounds()
+	scale := min(float64(width)/float64(b.Dx()), float64(height)/float64(b.Dy()))
+	return Scale(img, int(float64(b.Dx())*scale+0.5), int(float64(b.Dy())*scale+0.5))
+}
+
+func fill(img image.Image, width, height int) image.Image {
+	b := img.Bounds()
+	scale := max(float64(width)/float64(b.Dx()), float64(height)/float64(b.Dy()))
+	scaled := Scale(img, int(float64(b.Dx())*scale+0.5), int(float64(b.Dy())*scale+0.5))
+
+	return crop(scaled, width, height)
+}
+
+func crop(img image.Image, width, height int) image.Image {
+	b := img.Bounds()
+	x := b.Min.X + (b.Dx()-width)/2
+	y := b.Min.Y + (b.Dy()-height)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), img, image.Pt(x, y), draw.Src)
+	return dst
+}
+
+// pad centers the image on a transparent canvas of the given size. Images
+// larger than the canvas are cropped.
+func pad(img image.Image, width, height int) image.Image {
+	b := img.Bounds()
+	if b.Dx() == width && b.Dy() == height {
+		return img
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	offset := image.Pt((width-b.Dx())/2, (height-b.Dy())/2)
+	draw.Draw(dst, b.Sub(b.Min).Add(offset), img, b.Min, draw.Over)
+	return dst
+}
+
+func encode(w io.Writer, img image.Image, format string, quality int) error {
+	var err error
+	switch format {
+	case JPEG, "jpg":
+		if quality <= 0 || quality > 100 {
+			quality = DefaultQuality
+		}
+		err = jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case "gif":
+		err = gif.Encode(w, img, nil)
+	default:
+		// WebP can't be encoded by the standard library, it's saved as PNG
+		err = png.Encode(w, img)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode image: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := range width {
+		for y := range height {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           Options
+		width, height  int
+		format         string
+		transparentTop bool
+	}{
+		{name: "keep", opts: Options{Width: 100, Height: 100}, width: 200, height: 100, format: "png"},
+		{name: "fit", opts: Options{Width: 100, Height: 100, Resize: Fit}, width: 100, height: 50, format: "png"},
+		{name: "fit and pad", opts: Options{Width: 100, Height: 100, Resize: Fit, Pad: true}, width: 100, height: 100, format: "png", transparentTop: true},
+		{name: "fill", opts: Options{Width: 100, Height: 100, Resize: Fill}, width: 100, height: 100, format: "png"},
+		{name: "jpeg", opts: Options{Width: 50, Height: 80, Resize: Fill, Format: JPEG, Quality: 70}, width: 50, height: 80, format: "jpeg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Process(bytes.NewReader(testImage(200, 100)), &out, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+
+			img, format, err := image.Decode(&out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != tt.format {
+				t.Errorf("expected format %s, got %s", tt.format, format)
+			}
+			if b := img.Bounds(); b.Dx() != tt.width || b.Dy() != tt.height {
+				t.Errorf("expected %dx%d, got %dx%d", tt.width, tt.height, b.Dx(), b.Dy())
+			}
+			if _, _, _, a := img.At(50, 0).RGBA(); (a == 0) != tt.transparentTop {
+				t.Errorf("expected a transparent top: %v, got alpha %d", tt.transparentTop, a)
+			}
+		})
+	}
+}
+
+func TestProcessInvalidOptions(t *testing.T) {
+	tests := []struct {
+		opts     Options
+		expected error
+	}{
+		{Options{Resize: "stretch"}, UnknownModeErr},
+		{Options{Format: "bmp"}, UnknownFormatErr},
+	}
+
+	for _, tt := range tests {
+		err := Process(bytes.NewReader(testImage(10, 10)), &bytes.Buffer{}, tt.opts)
+		if !errors.Is(err, tt.expected) {
+			t.Errorf("expected %v, got %v", tt.expected, err)
+		}
+	}
+}
+
+func TestOptionsExtension(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"", ""},
+		{PNG, ".png"},
+		{JPEG, ".jpg"},
+		{"jpg", ".jpg"},
+	}
+
+	for _, tt := range tests {
+		if got := (Options{Format: tt.format}).Extension(); got != tt.expected {
+			t.Errorf("Extension() for %q = %q, expected %q", tt.format, got, tt.expected)
+		}
+	}
+}
+
+func TestOptionsOutputExtension(t *testing.T) {
+	tests := []struct {
+		options  Options
+		ext      string
+		expected string
+	}{
+		{Options{}, ".webp", ".webp"},
+		{Options{Resize: Fit}, ".jpg", ".jpg"},
+		{Options{Resize: Fit}, ".webp", ".png"},
+		{Options{Pad: true}, ".WEBP", ".png"},
+		{Options{Format: JPEG}, ".webp", ".jpg"},
+		{Options{Format: PNG}, ".jpg", ".png"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.options.OutputExtension(tt.ext); got != tt.expected {
+			t.Errorf("OutputExtension(%q) with %+v = %q, expected %q", tt.ext, tt.options, got, tt.expected)
+		}
+	}
+}
@@ -28,6 +28,7 @@ func listenForKeyboardEvents() {
 		sdl.SCANCODE_A:      "A",
 		sdl.SCANCODE_B:      "B",
 		sdl.SCANCODE_X:      "X",
+		sdl.SCANCODE_Y:      "Y",
 		sdl.SCANCODE_RETURN: "START",
 		sdl.SCANCODE_ESCAPE: "SELECT",
 	}
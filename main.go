@@ -9,6 +9,7 @@ import (
 
 	"github.com/anibaldeboni/screech/config"
 	"github.com/anibaldeboni/screech/input"
+	"github.com/anibaldeboni/screech/scraper"
 	"github.com/anibaldeboni/screech/screens"
 	"github.com/anibaldeboni/screech/uilib"
 
@@ -31,11 +32,36 @@ func main() {
 		}
 	}()
 
+	var (
+		headless, clearCache bool
+		refresh              string
+	)
 	flag.StringVar(&config.ConfigFile, "config", "screech.yaml", "Path to the configuration file")
+	flag.BoolVar(&headless, "headless", false, "Scrape the system dirs given as arguments without the graphical interface")
+	flag.BoolVar(&config.BypassCache, "no-cache", false, "Ignore cached game responses and query the server again")
+	flag.BoolVar(&clearCache, "clear-cache", false, "Remove all cached game responses before starting")
+	flag.StringVar(&refresh, "refresh", "", "Scrape again the images already scraped: all, older, smaller, changed or none, replacing the refresh mode of the config file")
 	flag.Parse()
 
 	config.InitVars()
 
+	if refresh != "" {
+		config.Refresh.Mode = refresh
+	}
+	if err := scraper.CheckRefreshMode(config.Refresh.Mode); err != nil {
+		log.Fatal(err)
+	}
+
+	if clearCache {
+		if err := scraper.ClearCache(); err != nil {
+			log.Println(err)
+		}
+	}
+
+	if headless {
+		os.Exit(screens.RunHeadless(flag.Args()))
+	}
+
 	if err := uilib.InitSDL(); err != nil {
 		panic(err)
 	}
@@ -86,14 +112,28 @@ func main() {
 		panic(err)
 	}
 
+	accountScreen, err := screens.NewAccountScreen(renderer)
+	if err != nil {
+		panic(err)
+	}
+
+	pickerScreen, err := screens.NewPickerScreen(renderer)
+	if err != nil {
+		panic(err)
+	}
+
 	screensMap := map[string]func(){
 		"home_screen":     homeScreen.Draw,
 		"scraping_screen": scrapingScreen.Draw,
+		"account_screen":  accountScreen.Draw,
+		"picker_screen":   pickerScreen.Draw,
 	}
 
 	inputHandlers := map[string]func(input.UserInputEvent){
 		"home_screen":     homeScreen.HandleInput,
 		"scraping_screen": scrapingScreen.HandleInput,
+		"account_screen":  accountScreen.HandleInput,
+		"picker_screen":   pickerScreen.HandleInput,
 	}
 
 	input.StartListening()
@@ -0,0 +1,38 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// FetchUserInfo returns the account details and quotas of the configured user.
+func FetchUserInfo(ctx context.Context) (UserInfo, error) {
+	var result UserInfoResponse
+
+	u, q := apiURL(UserInfoURL)
+	u.RawQuery = q.Encode()
+
+	res, err := limiter.get(ctx, u.String())
+	if err != nil {
+		return UserInfo{}, err
+	}
+
+	if err := json.Unmarshal(res, &result); err != nil {
+		return UserInfo{}, fmt.Errorf("failed to unmarshal JSON: %w response: %s", err, string(res))
+	}
+	user := result.Response.Ssuser
+	if user.ID == "" {
+		return UserInfo{}, InvalidCredentialsErr
+	}
+	limiter.seedUser(user)
+
+	return user, nil
+}
+
+// ValidateCredentials checks the configured username and password against
+// ScreenScraper so a scrape does not start with an account that will be refused.
+func ValidateCredentials(ctx context.Context) error {
+	_, err := FetchUserInfo(ctx)
+	return err
+}
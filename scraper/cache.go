@@ -0,0 +1,119 @@
+package scraper
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/anibaldeboni/screech/atomicfile"
+	"github.com/anibaldeboni/screech/config"
+	"github.com/anibaldeboni/screech/output"
+)
+
+const gamesCacheDir = "games"
+
+// gameCacheKey identifies the response of a rom lookup. Roms too large to be
+// hashed are told apart by their CRC, when in an archive, size and name.
+func gameCacheKey(systemID string, rom romLookup) string {
+	size := strconv.FormatInt(rom.Size, 10)
+	if rom.SHA1 != "" {
+		return cacheKey(systemID + ":" + rom.SHA1 + ":" + size)
+	}
+	if rom.Size == 0 {
+		return ""
+	}
+	return cacheKey(systemID + ":" + rom.CRC32 + ":" + size + ":" + rom.name)
+}
+
+// searchCacheKey identifies the response of a name search.
+func searchCacheKey(systemID, name string) string {
+	return cacheKey("search:" + systemID + ":" + name)
+}
+
+// gameIDCacheKey identifies the response of a lookup by game id.
+func gameIDCacheKey(systemID, gameID string) string {
+	return cacheKey("id:" + systemID + ":" + gameID)
+}
+
+func cacheKey(value string) string {
+	hash := sha1.Sum([]byte(value))
+	return hex.EncodeToString(hash[:])
+}
+
+func cacheFile(key string) string {
+	return filepath.Join(config.CacheDir(), gamesCacheDir, key+".json")
+}
+
+// cachedResponse returns a response stored by a previous lookup. The changed
+// refresh mode always asks the server, it compares the media CRCs of the game
+// with the ones of the images and a cached response would hide the changes.
+func cachedResponse(key string) ([]byte, bool) {
+	if key == "" || config.BypassCache || config.Cache.Disabled || config.Refresh.Mode == RefreshChanged {
+		return nil, false
+	}
+
+	file := cacheFile(key)
+	info, err := os.Stat(file)
+	if err != nil || time.Since(info.ModTime()) > config.Cache.TTL {
+		return nil, false
+	}
+
+	res, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+
+	return res, true
+}
+
+// CachedGame returns the game of a rom from the responses cached by previous
+// runs, without querying the server, e.g. to export roms that have nothing
+// left to scrape. Expired responses are used too, their metadata beats none.
+func CachedGame(systemID, romPath string) (Game, bool) {
+	if config.Cache.Disabled {
+		return Game{}, false
+	}
+
+	for _, lookup := range romLookups(romPath) {
+		key := gameCacheKey(systemID, lookup)
+		if key == "" {
+			continue
+		}
+		res, err := os.ReadFile(cacheFile(key))
+		if err != nil {
+			continue
+		}
+
+		var result GameInfoResponse
+		if err := json.Unmarshal(res, &result); err == nil && result.Response.Jeu.ID != "" {
+			game := result.Response.Jeu.toGame()
+			game.MatchedBy = MatchedByHash
+			return game, true
+		}
+	}
+
+	return Game{}, false
+}
+
+func storeResponse(key string, res []byte) {
+	if key == "" || config.Cache.Disabled {
+		return
+	}
+
+	if err := atomicfile.WriteFile(cacheFile(key), res); err != nil {
+		output.Printf("Error caching response: %v\n", err)
+	}
+}
+
+// ClearCache removes every cached game response.
+func ClearCache() error {
+	if err := os.RemoveAll(filepath.Join(config.CacheDir(), gamesCacheDir)); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+func TestGameResponseCache(t *testing.T) {
+	config.Cache.Dir = t.TempDir()
+	config.Cache.TTL = time.Hour
+	config.Cache.Disabled = false
+	config.BypassCache = false
+
+	key := gameCacheKey("4", romLookup{checksums: checksums{SHA1: "abc", Size: 42}})
+	if key == gameCacheKey("5", romLookup{checksums: checksums{SHA1: "abc", Size: 42}}) {
+		t.Fatal("expected keys to differ by system")
+	}
+
+	if _, ok := cachedResponse(key); ok {
+		t.Fatal("expected cache miss before storing")
+	}
+
+	storeResponse(key, []byte(`{"header":{}}`))
+	if res, ok := cachedResponse(key); !ok || string(res) != `{"header":{}}` {
+		t.Fatalf("expected cache hit, got %q", res)
+	}
+
+	config.BypassCache = true
+	if _, ok := cachedResponse(key); ok {
+		t.Error("expected cache to be bypassed")
+	}
+	config.BypassCache = false
+
+	expired := time.Now().Add(-2 * time.Hour)
+	_ = os.Chtimes(cacheFile(key), expired, expired)
+	if _, ok := cachedResponse(key); ok {
+		t.Error("expected expired entry to be ignored")
+	}
+
+	storeResponse(key, []byte(`{}`))
+	if err := ClearCache(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cachedResponse(key); ok {
+		t.Error("expected cache to be cleared")
+	}
+}
+
+func TestGameCacheKeyWithoutChecksum(t *testing.T) {
+	large := romLookup{name: "Game.zip", checksums: checksums{Size: 700 << 20}}
+	keys := map[string]romLookup{
+		"large rom":          large,
+		"other name":         {name: "Other.zip", checksums: large.checksums},
+		"other size":         {name: "Game.zip", checksums: checksums{Size: 650 << 20}},
+		"large archived rom": {name: "Game.bin", checksums: checksums{Size: 700 << 20, CRC32: "79520fa1"}},
+	}
+
+	seen := make(map[string]string)
+	for name, rom := range keys {
+		key := gameCacheKey("4", rom)
+		if key == "" {
+			t.Errorf("expected a key for the %s", name)
+		}
+		if other, ok := seen[key]; ok {
+			t.Errorf("expected the %s and the %s to have different keys", name, other)
+		}
+		seen[key] = name
+	}
+
+	if key := gameCacheKey("4", romLookup{name: "Game.zip"}); key != "" {
+		t.Errorf("expected no key for roms that could not be read, got %s", key)
+	}
+}
+
+func TestCachedResponseSeedsLimiter(t *testing.T) {
+	originalLimiter := limiter
+	defer func() { limiter = originalLimiter }()
+	limiter = newRateLimiter()
+
+	config.Cache.Dir = t.TempDir()
+	config.Cache.TTL = time.Hour
+	config.Cache.Disabled = false
+	config.BypassCache = false
+
+	rom := romLookup{name: "game.zip", checksums: checksums{SHA1: "abc", Size: 42}}
+	storeResponse(gameCacheKey("4", rom), []byte(`{"response":{"jeu":{"id":"1"},"ssuser":{"maxthreads":"4","maxrequestspermin":"60"}}}`))
+
+	if _, err := findGame(context.Background(), "4", rom); err != nil {
+		t.Fatal(err)
+	}
+	if limiter.maxThreads != 4 || limiter.interval != time.Second {
+		t.Errorf("expected the limits of the cached response, got %d threads every %v", limiter.maxThreads, limiter.interval)
+	}
+}
+
+func TestCachedGame(t *testing.T) {
+	config.Cache.Dir = t.TempDir()
+	config.Cache.TTL = time.Hour
+	config.Cache.Disabled = false
+
+	rom := filepath.Join(t.TempDir(), "game.sfc")
+	_ = os.WriteFile(rom, []byte("rom"), 0644)
+
+	if _, ok := CachedGame("4", rom); ok {
+		t.Fatal("expected no game before the rom was scraped")
+	}
+
+	lookups := romLookups(rom)
+	key := gameCacheKey("4", lookups[len(lookups)-1])
+	storeResponse(key, []byte(`{"response":{"jeu":{"id":"1234","noms":[{"region":"us","text":"Game"}]}}}`))
+
+	// Expired responses are still good for exports
+	expired := time.Now().Add(-2 * time.Hour)
+	_ = os.Chtimes(cacheFile(key), expired, expired)
+
+	game, ok := CachedGame("4", rom)
+	if !ok || game.ID != "1234" || game.Name != "Game" || game.MatchedBy != MatchedByHash {
+		t.Errorf("expected the cached game, got %+v", game)
+	}
+
+	config.Cache.Disabled = true
+	defer func() { config.Cache.Disabled = false }()
+	if _, ok := CachedGame("4", rom); ok {
+		t.Error("expected no game with the cache disabled")
+	}
+}
+
+func TestSearchAndGameIDResponsesCached(t *testing.T) {
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		_, _ = w.Write([]byte(`{"response":{"jeu":{"id":"42"},"jeux":[{"id":"42"}]}}`))
+	}))
+	defer server.Close()
+
+	originalBaseURL, originalSearchURL := BaseURL, SearchURL
+	defer func() { BaseURL, SearchURL = originalBaseURL, originalSearchURL }()
+	BaseURL, SearchURL = server.URL+"/game", server.URL+"/search"
+	config.Cache.Dir = t.TempDir()
+	config.Cache.TTL = time.Hour
+	config.Cache.Disabled = false
+	config.BypassCache = false
+
+	for range 2 {
+		if res, err := searchGames(context.Background(), "4", "Super Mario World"); err != nil || len(res.Response.Jeux) != 1 {
+			t.Fatalf("expected a search result, got %+v, %v", res, err)
+		}
+		if game, err := GameByID(context.Background(), "4", "42"); err != nil || game.ID != "42" {
+			t.Fatalf("expected game 42, got %+v, %v", game, err)
+		}
+	}
+
+	if requests["/search"] != 1 || requests["/game"] != 1 {
+		t.Errorf("expected a single request of each lookup, got %v", requests)
+	}
+}
+
+func TestChangedRefreshSkipsCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"response":{"jeu":{"id":"1","medias":[{"type":"box-2D","crc":"22222222"}]}}}`))
+	}))
+	defer server.Close()
+
+	originalBaseURL, originalRefresh := BaseURL, config.Refresh
+	defer func() { BaseURL, config.Refresh = originalBaseURL, originalRefresh }()
+	BaseURL = server.URL
+	config.Cache.Dir = t.TempDir()
+	config.Cache.TTL = time.Hour
+	config.Cache.Disabled = false
+	config.BypassCache = false
+
+	// The image changed on the server since the response was cached
+	rom := romLookup{name: "game.zip", checksums: checksums{SHA1: "abc", Size: 42}}
+	storeResponse(gameCacheKey("4", rom), []byte(`{"response":{"jeu":{"id":"1","medias":[{"type":"box-2D","crc":"11111111"}]}}}`))
+
+	tests := []struct {
+		mode     string
+		expected string
+	}{
+		{RefreshAll, "11111111"},
+		{RefreshChanged, "22222222"},
+	}
+	for _, tt := range tests {
+		config.Refresh.Mode = tt.mode
+		res, err := findGame(context.Background(), "4", rom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if crc := res.Response.Jeu.Medias[0].Crc; crc != tt.expected {
+			t.Errorf("expected the %s mode to see CRC %s, got %s", tt.mode, tt.expected, crc)
+		}
+	}
+}
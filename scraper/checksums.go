@@ -0,0 +1,239 @@
+package scraper
+
+import (
+	"archive/zip"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/anibaldeboni/screech/atomicfile"
+	"github.com/anibaldeboni/screech/config"
+)
+
+const (
+	maxFileSizeBytes = 104857600 // 100MB
+	hashIndexFile    = "hashes.json"
+)
+
+type checksums struct {
+	Size  int64  `json:"size"`
+	CRC32 string `json:"crc32,omitempty"`
+	MD5   string `json:"md5,omitempty"`
+	SHA1  string `json:"sha1,omitempty"`
+}
+
+// archivedRom is the single rom file found inside an archive.
+type archivedRom struct {
+	Name string `json:"name"`
+	checksums
+}
+
+type romHashes struct {
+	checksums
+	Archived *archivedRom `json:"archived,omitempty"`
+}
+
+type hashIndexEntry struct {
+	romHashes
+	ModTime int64 `json:"mtime"`
+}
+
+// hashIndex remembers the checksums of every rom hashed so far, so unchanged
+// files are not read again on the next run.
+type hashIndex struct {
+	mu      sync.Mutex
+	entries map[string]hashIndexEntry
+	loaded  bool
+	dirty   bool
+}
+
+var romIndex = &hashIndex{}
+
+func romChecksums(filePath string) romHashes {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return romHashes{}
+	}
+
+	path, err := filepath.Abs(filePath)
+	if err != nil {
+		path = filePath
+	}
+
+	if entry, ok := romIndex.lookup(path, info); ok {
+		return entry.romHashes
+	}
+
+	sums, err := hashFile(filePath, info.Size())
+	if err != nil {
+		return romHashes{checksums: checksums{Size: info.Size()}}
+	}
+	hashes := romHashes{checksums: sums}
+
+	if strings.EqualFold(filepath.Ext(filePath), ".zip") {
+		if archived, err := hashZippedRom(filePath); err == nil {
+			hashes.Archived = archived
+		}
+	}
+
+	romIndex.store(path, hashIndexEntry{romHashes: hashes, ModTime: info.ModTime().UnixNano()})
+
+	return hashes
+}
+
+// hashZippedRom hashes the only rom file of a zip archive. The CRC32 comes
+// from the archive itself, MD5 and SHA1 are computed from the decompressed
+// contents.
+func hashZippedRom(filePath string) (*archivedRom, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	var rom *zip.File
+	for _, file := range archive.File {
+		if !isArchivedRom(file) {
+			continue
+		}
+		if rom != nil {
+			return nil, fmt.Errorf("archive contains more than one rom: %s", filePath)
+		}
+		rom = file
+	}
+	if rom == nil {
+		return nil, fmt.Errorf("archive does not contain a rom: %s", filePath)
+	}
+
+	archived := &archivedRom{
+		Name: filepath.Base(rom.Name),
+		checksums: checksums{
+			Size:  int64(rom.UncompressedSize64),
+			CRC32: fmt.Sprintf("%08x", rom.CRC32),
+		},
+	}
+	if archived.Size > maxFileSizeBytes {
+		return archived, nil
+	}
+
+	reader, err := rom.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	md5Hash, sha1Hash := md5.New(), sha1.New()
+	if _, err := io.Copy(io.MultiWriter(md5Hash, sha1Hash), reader); err != nil {
+		return nil, err
+	}
+	archived.MD5 = hex.EncodeToString(md5Hash.Sum(nil))
+	archived.SHA1 = hex.EncodeToString(sha1Hash.Sum(nil))
+
+	return archived, nil
+}
+
+func isArchivedRom(file *zip.File) bool {
+	name := filepath.Base(file.Name)
+	return !file.FileInfo().IsDir() &&
+		!strings.HasPrefix(name, ".") &&
+		!strings.HasPrefix(file.Name, "__MACOSX/") &&
+		!slices.Contains(config.ExcludeExtensions, strings.ToLower(filepath.Ext(name)))
+}
+
+func hashFile(filePath string, size int64) (checksums, error) {
+	sums := checksums{Size: size}
+	if size > maxFileSizeBytes {
+		return sums, nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return sums, err
+	}
+	defer file.Close()
+
+	crcHash, md5Hash, sha1Hash := crc32.NewIEEE(), md5.New(), sha1.New()
+	if _, err := io.Copy(io.MultiWriter(crcHash, md5Hash, sha1Hash), file); err != nil {
+		return sums, err
+	}
+
+	sums.CRC32 = hex.EncodeToString(crcHash.Sum(nil))
+	sums.MD5 = hex.EncodeToString(md5Hash.Sum(nil))
+	sums.SHA1 = hex.EncodeToString(sha1Hash.Sum(nil))
+
+	return sums, nil
+}
+
+func (i *hashIndex) lookup(path string, info os.FileInfo) (hashIndexEntry, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.load()
+
+	entry, ok := i.entries[path]
+	if !ok || entry.Size != info.Size() || entry.ModTime != info.ModTime().UnixNano() {
+		return hashIndexEntry{}, false
+	}
+
+	return entry, true
+}
+
+func (i *hashIndex) store(path string, entry hashIndexEntry) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.load()
+
+	i.entries[path] = entry
+	i.dirty = true
+}
+
+func (i *hashIndex) load() {
+	if i.loaded {
+		return
+	}
+	i.loaded = true
+	i.entries = make(map[string]hashIndexEntry)
+
+	file, err := os.ReadFile(filepath.Join(config.CacheDir(), hashIndexFile))
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(file, &i.entries)
+}
+
+func (i *hashIndex) save() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if !i.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(i.entries)
+	if err != nil {
+		return err
+	}
+
+	if err := atomicfile.WriteFile(filepath.Join(config.CacheDir(), hashIndexFile), data); err != nil {
+		return err
+	}
+	i.dirty = false
+
+	return nil
+}
+
+// SaveHashIndex persists the checksums computed during the run.
+func SaveHashIndex() error {
+	if err := romIndex.save(); err != nil {
+		return fmt.Errorf("failed to save hash index: %w", err)
+	}
+	return nil
+}
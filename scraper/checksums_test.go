@@ -0,0 +1,137 @@
+package scraper
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+func TestRomChecksums(t *testing.T) {
+	config.Cache.Dir = t.TempDir()
+	romIndex = &hashIndex{}
+
+	rom := filepath.Join(t.TempDir(), "game.sfc")
+	_ = os.WriteFile(rom, []byte("rom"), 0644)
+
+	expected := checksums{
+		Size:  3,
+		CRC32: "79520fa1",
+		MD5:   "5f397a1e588cfe96b4aa4bab7a5b1d44",
+		SHA1:  "a1e17a20e93e5da710685444df3ad038ac66e2c9",
+	}
+
+	if sums := romChecksums(rom); sums.checksums != expected || sums.Archived != nil {
+		t.Fatalf("expected %+v, got %+v", expected, sums)
+	}
+
+	if err := SaveHashIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh index must reuse the stored checksums while the file is unchanged
+	romIndex = &hashIndex{}
+	path, _ := filepath.Abs(rom)
+	entry, ok := romIndex.lookup(path, mustStat(t, rom))
+	if !ok || entry.checksums != expected {
+		t.Fatalf("expected stored checksums %+v, got %+v (found: %v)", expected, entry.checksums, ok)
+	}
+
+	later := time.Now().Add(time.Hour)
+	_ = os.WriteFile(rom, []byte("changed rom"), 0644)
+	_ = os.Chtimes(rom, later, later)
+
+	if sums := romChecksums(rom); sums.Size != 11 || sums.SHA1 == expected.SHA1 {
+		t.Errorf("expected changed file to be hashed again, got %+v", sums)
+	}
+}
+
+func mustStat(t *testing.T, path string) os.FileInfo {
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestHashZippedRom(t *testing.T) {
+	config.ExcludeExtensions = []string{".txt"}
+
+	tests := []struct {
+		name      string
+		files     map[string]string
+		expected  *archivedRom
+		expectErr bool
+	}{
+		{
+			name:  "Single rom with extra files",
+			files: map[string]string{"Game (USA).sfc": "rom", "readme.txt": "read me", "__MACOSX/._Game (USA).sfc": "meta"},
+			expected: &archivedRom{
+				Name: "Game (USA).sfc",
+				checksums: checksums{
+					Size:  3,
+					CRC32: "79520fa1",
+					MD5:   "5f397a1e588cfe96b4aa4bab7a5b1d44",
+					SHA1:  "a1e17a20e93e5da710685444df3ad038ac66e2c9",
+				},
+			},
+		},
+		{
+			name:      "More than one rom",
+			files:     map[string]string{"Game (USA).sfc": "rom", "Game (Europe).sfc": "rom"},
+			expectErr: true,
+		},
+		{
+			name:      "No rom",
+			files:     map[string]string{"readme.txt": "read me"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := writeZip(t, tt.files)
+
+			archived, err := hashZippedRom(archive)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error but got: %v", err)
+			}
+
+			if *archived != *tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, archived)
+			}
+		})
+	}
+}
+
+func writeZip(t *testing.T, files map[string]string) string {
+	path := filepath.Join(t.TempDir(), "game.zip")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+	for name, content := range files {
+		w, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
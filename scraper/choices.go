@@ -0,0 +1,69 @@
+package scraper
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/anibaldeboni/screech/atomicfile"
+	"github.com/anibaldeboni/screech/config"
+	"github.com/anibaldeboni/screech/output"
+)
+
+const choicesFile = "choices.json"
+
+// choiceStore keeps the games picked by the user for ambiguous roms, keyed by
+// system and rom file name.
+type choiceStore struct {
+	mu      sync.Mutex
+	entries map[string]string
+	loaded  bool
+}
+
+var matchChoices = &choiceStore{}
+
+func choiceKey(systemID, romPath string) string {
+	return systemID + ":" + filepath.Base(romPath)
+}
+
+func chosenGame(systemID, romPath string) (string, bool) {
+	matchChoices.mu.Lock()
+	defer matchChoices.mu.Unlock()
+	matchChoices.load()
+
+	gameID, ok := matchChoices.entries[choiceKey(systemID, romPath)]
+	return gameID, ok
+}
+
+// SaveChoice remembers the game picked for a rom so later runs use it without
+// asking again.
+func SaveChoice(systemID, romPath, gameID string) {
+	matchChoices.mu.Lock()
+	defer matchChoices.mu.Unlock()
+	matchChoices.load()
+
+	matchChoices.entries[choiceKey(systemID, romPath)] = gameID
+
+	data, err := json.Marshal(matchChoices.entries)
+	if err == nil {
+		err = atomicfile.WriteFile(filepath.Join(config.CacheDir(), choicesFile), data)
+	}
+	if err != nil {
+		output.Printf("Error saving the chosen game: %v\n", err)
+	}
+}
+
+func (c *choiceStore) load() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	c.entries = make(map[string]string)
+
+	file, err := os.ReadFile(filepath.Join(config.CacheDir(), choicesFile))
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(file, &c.entries)
+}
@@ -2,12 +2,9 @@ package scraper
 
 import (
 	"context"
-	"crypto/sha1"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -19,75 +16,135 @@ import (
 	"github.com/anibaldeboni/screech/config"
 )
 
-type MediaType string
-
 var (
 	DevID       = "1234"
 	DevPassword = "password"
 	BaseURL     = "https://www.screenscraper.fr/api2/jeuInfos.php"
+	UserInfoURL = "https://www.screenscraper.fr/api2/ssuserInfos.php"
 
 	EmptyBodyErr = errors.New("empty body")
 	// APIClosedErr          = errors.New("API closed")
 	HTTPRequestErr        = errors.New("error making HTTP request")
 	HTTPRequestAbortedErr = errors.New("request aborted")
-	UnknownMediaTypeErr   = errors.New("unknown media type, choose among box-2D, box-3D, mixrbv1, mixrbv2")
-
-	Box2D MediaType = "box-2D"
-	Box3D MediaType = "box-3D"
-	MixV1 MediaType = "mixrbv1"
-	MixV2 MediaType = "mixrbv2"
+	UnknownMediaTypeErr   = errors.New("unknown media type")
 )
 
-const maxFileSizeBytes = 104857600 // 100MB
-
 func FindGame(ctx context.Context, systemID string, romName string) (GameInfoResponse, error) {
+	var (
+		result GameInfoResponse
+		err    error
+	)
+
+	// Zipped roms are looked up by the rom inside the archive first, the
+	// archive itself is only a fallback since its hash never matches.
+	for _, lookup := range romLookups(romName) {
+		result, err = findGame(ctx, systemID, lookup)
+		if !errors.Is(err, GameNotFoundErr) {
+			break
+		}
+	}
+
+	return result, err
+}
+
+// GameByID fetches a game by its ScreenScraper id, e.g. one the user picked.
+func GameByID(ctx context.Context, systemID string, gameID string) (Game, error) {
 	var result GameInfoResponse
 
-	res, err := get(ctx, parseFindGameURL(systemID, romName))
+	key := gameIDCacheKey(systemID, gameID)
+	if res, ok := cachedResponse(key); ok {
+		if err := json.Unmarshal(res, &result); err == nil {
+			limiter.seedUser(result.Response.Ssuser)
+			return result.Response.Jeu.toGame(), nil
+		}
+	}
+
+	u, q := apiURL(BaseURL)
+	q.Set("systemeid", systemID)
+	q.Set("gameid", gameID)
+	u.RawQuery = q.Encode()
+
+	res, err := limiter.get(ctx, u.String())
 	if err != nil {
-		return result, err
+		return Game{}, err
 	}
 
 	if err := json.Unmarshal(res, &result); err != nil {
-		return result, fmt.Errorf("failed to unmarshal JSON: %w response: %s", err, string(res))
+		return Game{}, fmt.Errorf("failed to unmarshal JSON: %w response: %s", err, string(res))
 	}
+	limiter.seedUser(result.Response.Ssuser)
 
-	return result, nil
+	storeResponse(key, res)
+
+	return result.Response.Jeu.toGame(), nil
 }
 
-func DownloadMedia(ctx context.Context, medias []Media, mediaType MediaType, dest string) error {
-	if err := checkDestination(dest); err != nil {
-		return err
-	}
+type romLookup struct {
+	name string
+	checksums
+}
 
-	if err := checkMediaType(mediaType); err != nil {
-		return err
+func romLookups(romName string) []romLookup {
+	hashes := romChecksums(romName)
+	lookups := make([]romLookup, 0, 2)
+	if hashes.Archived != nil {
+		lookups = append(lookups, romLookup{name: hashes.Archived.Name, checksums: hashes.Archived.checksums})
 	}
 
-	mediaURL, err := findMediaURLByRegion(medias, mediaType)
-	if err != nil {
-		return err
-	}
+	return append(lookups, romLookup{name: cleanRomName(romName) + ".zip", checksums: hashes.checksums})
+}
 
-	mediaURL, err = addWHToMediaURL(mediaURL)
-	if err != nil {
-		return err
+func findGame(ctx context.Context, systemID string, rom romLookup) (GameInfoResponse, error) {
+	var result GameInfoResponse
+
+	key := gameCacheKey(systemID, rom)
+	if res, ok := cachedResponse(key); ok {
+		if err := json.Unmarshal(res, &result); err == nil {
+			// The account limits of the cached response still apply to the
+			// live requests and downloads that follow
+			limiter.seedUser(result.Response.Ssuser)
+			return result, nil
+		}
 	}
 
-	res, err := get(ctx, mediaURL)
+	res, err := limiter.get(ctx, parseFindGameURL(systemID, rom))
 	if err != nil {
-		return err
+		return result, err
 	}
 
-	if err := saveToDisk(dest, res); err != nil {
-		return err
+	if err := json.Unmarshal(res, &result); err != nil {
+		return result, fmt.Errorf("failed to unmarshal JSON: %w response: %s", err, string(res))
 	}
+	limiter.seedUser(result.Response.Ssuser)
 
-	return nil
+	storeResponse(key, res)
+
+	return result, nil
+}
+
+func parseFindGameURL(systemID string, rom romLookup) string {
+	u, q := apiURL(BaseURL)
+	q.Set("systemeid", systemID)
+	q.Set("romtype", "rom")
+	q.Set("romnom", rom.name)
+	q.Set("romtaille", strconv.FormatInt(rom.Size, 10))
+	if rom.CRC32 != "" {
+		q.Set("crc", rom.CRC32)
+	}
+	if rom.MD5 != "" {
+		q.Set("md5", rom.MD5)
+	}
+	if rom.SHA1 != "" {
+		q.Set("sha1", rom.SHA1)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
 }
 
-func parseFindGameURL(systemID, romName string) string {
-	u, _ := url.Parse(BaseURL)
+// apiURL parses an API endpoint and returns it along with the query carrying
+// the app and user credentials every ScreenScraper call needs.
+func apiURL(endpoint string) (*url.URL, url.Values) {
+	u, _ := url.Parse(endpoint)
 	q := u.Query()
 	q.Set("devid", DevID)
 	q.Set("devpassword", DevPassword)
@@ -95,13 +152,7 @@ func parseFindGameURL(systemID, romName string) string {
 	q.Set("output", "json")
 	q.Set("ssid", config.Username)
 	q.Set("sspassword", config.Password)
-	q.Set("sha1", SHA1Sum(romName))
-	q.Set("systemeid", systemID)
-	q.Set("romtype", "rom")
-	q.Set("romnom", cleanRomName(romName)+".zip")
-	q.Set("romtaille", strconv.FormatInt(fileSize(romName), 10))
-	u.RawQuery = q.Encode()
-	return u.String()
+	return u, q
 }
 
 func filterMediasByType(medias []Media, mediaType MediaType) []Media {
@@ -114,55 +165,80 @@ func filterMediasByType(medias []Media, mediaType MediaType) []Media {
 	return filtered
 }
 
-func findMediaURLByRegion(medias []Media, mediaType MediaType) (mediaURL string, err error) {
-	mediasByType := filterMediasByType(medias, mediaType)
-	if len(mediasByType) == 0 {
-		return mediaURL, fmt.Errorf("media not found for type: %s", mediaType)
+// findMedia walks the media types in order, trying the configured regions of
+// a type before moving on to the next one.
+func findMedia(medias []Media, spec config.ScrapeMedia) (Media, error) {
+	var found bool
+	for _, mediaType := range spec.Type {
+		mediasByType := filterMediasByType(medias, MediaType(mediaType))
+		if len(mediasByType) == 0 {
+			continue
+		}
+		found = true
+
+		if media, ok := findMediaByRegion(mediasByType, spec.Regions); ok {
+			return media, nil
+		}
+	}
+
+	if !found {
+		return Media{}, fmt.Errorf("media not found for type: %s", spec.Type)
+	}
+
+	if spec.IgnoreMissingRegion {
+		for _, mediaType := range spec.Type {
+			if mediasByType := filterMediasByType(medias, MediaType(mediaType)); len(mediasByType) > 0 {
+				return mediasByType[0], nil
+			}
+		}
 	}
 
-findmedia:
-	for _, r := range config.Media.Regions {
-		for _, media := range mediasByType {
+	return Media{}, fmt.Errorf("media not found for regions: %s", spec.Regions)
+}
+
+func findMediaByRegion(medias []Media, regions []string) (Media, bool) {
+	for _, r := range regions {
+		for _, media := range medias {
 			if media.Region == r {
-				mediaURL = media.URL
-				break findmedia
+				return media, true
 			}
 		}
 	}
 
-	if mediaURL == "" {
-		if config.Media.IgnoreMissingRegion {
-			mediaURL = mediasByType[0].URL
-		} else {
-			return mediaURL, fmt.Errorf("media not found for regions: %s", config.Media.Regions)
+	// Media without region, e.g. screenshots, fit any region
+	for _, media := range medias {
+		if media.Region == "" {
+			return media, true
 		}
 	}
 
-	return mediaURL, nil
+	return Media{}, false
 }
 
-func addWHToMediaURL(mediaURL string) (string, error) {
-	u, err := url.Parse(mediaURL)
+func addWHToMediaURL(media Media) (string, error) {
+	u, err := url.Parse(media.URL)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse media URL: %w", err)
 	}
+	// Only images can be resized
+	if MediaType(media.Type).Format() != ImageFormat {
+		return u.String(), nil
+	}
+	width, height := config.Boxart.Width, config.Boxart.Height
+	if media.maxWidth > 0 {
+		width = int(media.maxWidth)
+	}
+	if media.maxHeight > 0 {
+		height = int(media.maxHeight)
+	}
 	q := u.Query()
-	q.Set("maxwidth", strconv.Itoa(config.Boxart.Width))
-	q.Set("maxheight", strconv.Itoa(config.Boxart.Height))
+	q.Set("maxwidth", strconv.Itoa(width))
+	q.Set("maxheight", strconv.Itoa(height))
 	u.RawQuery = q.Encode()
 
 	return u.String(), nil
 }
 
-func checkMediaType(mediaType MediaType) error {
-	switch mediaType {
-	case Box2D, Box3D, MixV1, MixV2:
-		return nil
-	default:
-		return UnknownMediaTypeErr
-	}
-}
-
 func checkDestination(dest string) error {
 	if _, err := os.Stat(dest); err == nil {
 		return fmt.Errorf("destination file already exists: %s", dest)
@@ -173,7 +249,7 @@ func checkDestination(dest string) error {
 	return nil
 }
 
-func get(ctx context.Context, url string) ([]byte, error) {
+func send(ctx context.Context, url string) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
@@ -185,40 +261,8 @@ func get(ctx context.Context, url string) ([]byte, error) {
 		}
 		return nil, HTTPRequestErr
 	}
-	defer res.Body.Close()
 
-	return handleResponse(res)
-}
-
-func saveToDisk(dest string, file []byte) error {
-	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
-		return fmt.Errorf("failed to create destination directory: %w", err)
-	}
-
-	if err := os.WriteFile(dest, file, os.ModePerm); err != nil {
-		return fmt.Errorf("failed to write file to disk: %w", err)
-	}
-
-	return nil
-}
-
-func SHA1Sum(filePath string) string {
-	if fileSize(filePath) > maxFileSizeBytes {
-		return ""
-	}
-
-	file, err := os.Open(filePath)
-	if err != nil {
-		return ""
-	}
-	defer file.Close()
-
-	hash := sha1.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return ""
-	}
-
-	return hex.EncodeToString(hash.Sum(nil))
+	return res, nil
 }
 
 func cleanRomName(file string) string {
@@ -241,16 +285,3 @@ func cleanSpaces(input string) string {
 			ReplaceAllString(input, " "),
 	)
 }
-
-func fileSize(filePath string) int64 {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return 0
-	}
-	defer file.Close()
-	fi, err := file.Stat()
-	if err != nil {
-		return 0
-	}
-	return fi.Size()
-}
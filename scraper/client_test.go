@@ -0,0 +1,66 @@
+package scraper
+
+import (
+	"testing"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+func TestFindMediaTypeFallback(t *testing.T) {
+	medias := []Media{
+		{Type: "box-2D", Region: "jp", URL: "box-2D-jp"},
+		{Type: "box-2D", Region: "us", URL: "box-2D-us"},
+		{Type: "mixrbv2", Region: "eu", URL: "mixrbv2-eu"},
+		{Type: "ss", URL: "ss"},
+	}
+
+	tests := []struct {
+		name     string
+		spec     config.ScrapeMedia
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "first available type in a configured region",
+			spec:     config.ScrapeMedia{Type: config.MediaTypes{"box-3D", "box-2D", "ss"}, Regions: []string{"eu", "us"}},
+			expected: "box-2D-us",
+		},
+		{
+			name:     "next type when the regions are missing",
+			spec:     config.ScrapeMedia{Type: config.MediaTypes{"box-2D", "mixrbv2"}, Regions: []string{"eu"}},
+			expected: "mixrbv2-eu",
+		},
+		{
+			name:     "region-less media",
+			spec:     config.ScrapeMedia{Type: config.MediaTypes{"box-3D", "ss"}, Regions: []string{"br"}},
+			expected: "ss",
+		},
+		{
+			name:     "ignore missing region picks the first type found",
+			spec:     config.ScrapeMedia{Type: config.MediaTypes{"box-3D", "box-2D", "mixrbv2"}, Regions: []string{"br"}, IgnoreMissingRegion: true},
+			expected: "box-2D-jp",
+		},
+		{
+			name:    "no region match",
+			spec:    config.ScrapeMedia{Type: config.MediaTypes{"box-2D", "mixrbv2"}, Regions: []string{"br"}},
+			wantErr: true,
+		},
+		{
+			name:    "no type match",
+			spec:    config.ScrapeMedia{Type: config.MediaTypes{"box-3D", "sstitle"}, Regions: []string{"us"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			media, err := findMedia(medias, tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got %v", tt.wantErr, err)
+			}
+			if media.URL != tt.expected {
+				t.Errorf("expected media %q, got %q", tt.expected, media.URL)
+			}
+		})
+	}
+}
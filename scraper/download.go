@@ -0,0 +1,81 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/anibaldeboni/screech/atomicfile"
+)
+
+// maxMediaSize is the largest media file saved, anything bigger is not what
+// was asked for.
+var maxMediaSize int64 = 512 << 20
+
+var MediaTooLargeErr = errors.New("media file is too large")
+
+// bodyReader tells the errors of a response body apart: a cancelled transfer
+// aborts the scraping, a dropped one can be tried again.
+type bodyReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	if err != nil && err != io.EOF {
+		if b.ctx.Err() != nil || errors.Is(err, HTTPRequestAbortedErr) {
+			return n, HTTPRequestAbortedErr
+		}
+		return n, errors.Join(UnreadableBodyErr, err)
+	}
+	return n, err
+}
+
+// download hands the body of the response to fn as it is received, fn must
+// not keep it past its return.
+func download(ctx context.Context, url string, fn func(io.Reader) error) error {
+	res, err := send(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return readResponse(ctx, res, res.Body, fn)
+}
+
+// readResponse passes the body of a successful response to fn, the errors of
+// the others are read as for any other request.
+func readResponse(ctx context.Context, res *http.Response, body io.Reader, fn func(io.Reader) error) error {
+	if res.StatusCode != http.StatusOK {
+		_, err := handleResponse(res)
+		return err
+	}
+	return fn(&bodyReader{ctx: ctx, reader: body})
+}
+
+// saveMedia streams a media file to dest, verifying it along the way.
+func saveMedia(dest string, media Media, r io.Reader, resizable bool) error {
+	verifier := newMediaVerifier(media, resizable)
+	return writeStream(dest, io.TeeReader(r, verifier), func(string) error {
+		return verifier.verify()
+	})
+}
+
+// writeStream writes r to dest, see atomicfile.Write, refusing files larger
+// than maxMediaSize. check, when set, is given the complete temporary file
+// before it replaces dest.
+func writeStream(dest string, r io.Reader, check func(tmp string) error) error {
+	limited := &io.LimitedReader{R: r, N: maxMediaSize + 1}
+	return atomicfile.Write(dest, limited, func(tmp string) error {
+		if limited.N == 0 {
+			return fmt.Errorf("%w: more than %d bytes", MediaTooLargeErr, maxMediaSize)
+		}
+		if check == nil {
+			return nil
+		}
+		return check(tmp)
+	})
+}
@@ -0,0 +1,94 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// assertDirFiles checks the files left in dir, temporary files included.
+func assertDirFiles(t *testing.T, dir string, expected ...string) {
+	t.Helper()
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected the files %v, got %v", expected, names)
+	}
+}
+
+func TestWriteStream(t *testing.T) {
+	defer func(size int64) { maxMediaSize = size }(maxMediaSize)
+	maxMediaSize = 8
+
+	checkErr := errors.New("check failed")
+	tests := []struct {
+		name      string
+		data      string
+		check     func(tmp string) error
+		expectErr error
+	}{
+		{name: "Complete file", data: "content"},
+		{name: "Failed check", data: "content", check: func(string) error { return checkErr }, expectErr: checkErr},
+		{name: "Too large", data: "too much content", expectErr: MediaTooLargeErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dest := filepath.Join(dir, "game.png")
+
+			err := writeStream(dest, strings.NewReader(tt.data), tt.check)
+			if tt.expectErr == nil {
+				if err != nil {
+					t.Fatalf("did not expect error but got: %v", err)
+				}
+				info, _ := os.Stat(dest)
+				if data, _ := os.ReadFile(dest); string(data) != tt.data || info.Mode().Perm() != 0644 {
+					t.Errorf("expected %q with mode 0644, got %q with mode %v", tt.data, data, info.Mode().Perm())
+				}
+				assertDirFiles(t, dir, "game.png")
+				return
+			}
+
+			if !errors.Is(err, tt.expectErr) {
+				t.Errorf("expected error %v, got %v", tt.expectErr, err)
+			}
+			assertDirFiles(t, dir)
+		})
+	}
+}
+
+func TestFetchMediaCancelled(t *testing.T) {
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		_, _ = w.Write([]byte("\x00\x00\x00\x18ftypmp42"))
+		w.(http.Flusher).Flush()
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	dir := t.TempDir()
+	media := Media{Type: "video", URL: server.URL}
+	err := (ScreenScraper{}).FetchMedia(ctx, media, filepath.Join(dir, "game.mp4"))
+	if !errors.Is(err, HTTPRequestAbortedErr) {
+		t.Errorf("expected request aborted error, got %v", err)
+	}
+	assertDirFiles(t, dir)
+}
@@ -6,7 +6,9 @@ import (
 	"io"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 )
 
@@ -22,8 +24,78 @@ var (
 	RomFileNameErr         = errors.New("rom file name is not correct")
 	DevLoginErr            = errors.New("Screech cannot access the server")
 	BadRequestErr          = errors.New("bad request")
+	InvalidCredentialsErr  = errors.New("invalid ScreenScraper username or password")
+	CorruptMediaErr        = errors.New("corrupt media download")
+
+	retryableErrs = []error{
+		ServerLockedErr,
+		ServerOverloadedErr,
+		TooManyRequestsErr,
+		HTTPRequestErr,
+		UnreadableBodyErr,
+		CorruptMediaErr,
+	}
+	fatalErrs = []error{
+		ScrapeQuotaErr,
+		AppHasBeenBlockedErr,
+		DevLoginErr,
+		ToowManyUnknownRomsErr,
+		InvalidCredentialsErr,
+	}
 )
 
+// statusCodeErr is returned for status codes without a known meaning.
+type statusCodeErr struct {
+	code int
+	body string
+}
+
+func (e *statusCodeErr) Error() string {
+	return fmt.Sprintf("unexpected status code: %d, response: %s", e.code, e.body)
+}
+
+// retryAfterErr carries the delay the server asked for before trying again.
+type retryAfterErr struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterErr) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryAfterErr) Unwrap() error {
+	return e.err
+}
+
+// IsRetryable reports whether a failed request may succeed when tried again,
+// e.g. the server is overloaded or the connection dropped.
+func IsRetryable(err error) bool {
+	if errors.Is(err, HTTPRequestAbortedErr) {
+		return false
+	}
+
+	for _, retryable := range retryableErrs {
+		if errors.Is(err, retryable) {
+			return true
+		}
+	}
+
+	var statusErr *statusCodeErr
+	return errors.As(err, &statusErr) && statusErr.code >= http.StatusInternalServerError
+}
+
+// IsFatal reports whether the error is tied to the account or the app rather
+// than to a single rom, so every following request will fail as well.
+func IsFatal(err error) bool {
+	for _, fatal := range fatalErrs {
+		if errors.Is(err, fatal) {
+			return true
+		}
+	}
+	return false
+}
+
 func handleResponse(res *http.Response) ([]byte, error) {
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
@@ -36,7 +108,7 @@ func handleResponse(res *http.Response) ([]byte, error) {
 	case http.StatusBadRequest:
 		return nil, handleBadRequest(string(body))
 	case http.StatusUnauthorized:
-		return nil, ServerOverloadedErr
+		return nil, withRetryAfter(res, ServerOverloadedErr)
 	case http.StatusForbidden:
 		return nil, DevLoginErr
 	case http.StatusNotFound:
@@ -46,7 +118,7 @@ func handleResponse(res *http.Response) ([]byte, error) {
 	case http.StatusUpgradeRequired:
 		return nil, AppHasBeenBlockedErr
 	case http.StatusTooManyRequests:
-		return nil, TooManyRequestsErr
+		return nil, withRetryAfter(res, TooManyRequestsErr)
 	case http.StatusRequestHeaderFieldsTooLarge:
 		return nil, ToowManyUnknownRomsErr
 	case 430:
@@ -55,12 +127,28 @@ func handleResponse(res *http.Response) ([]byte, error) {
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d, response: %s", res.StatusCode, string(body))
+		return nil, withRetryAfter(res, &statusCodeErr{code: res.StatusCode, body: string(body)})
 	}
 
 	return body, nil
 }
 
+func withRetryAfter(res *http.Response, err error) error {
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return err
+	}
+
+	if seconds, convErr := strconv.Atoi(value); convErr == nil {
+		return &retryAfterErr{err: err, delay: time.Duration(seconds) * time.Second}
+	}
+	if date, parseErr := http.ParseTime(value); parseErr == nil {
+		return &retryAfterErr{err: err, delay: time.Until(date)}
+	}
+
+	return err
+}
+
 func handleBadRequest(message string) error {
 	if strings.Contains(message, "nom du fichier rom") {
 		return RomFileNameErr
@@ -102,3 +190,15 @@ func isFieldError(s string) bool {
 	re := regexp.MustCompile(`(?i)champ\s+.*?\s+errone`)
 	return re.MatchString(normalized)
 }
+
+// QuotaResetTime returns when the daily quotas reset, at midnight on the
+// server clock in France.
+func QuotaResetTime(now time.Time) time.Time {
+	location, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		location = time.FixedZone("CET", 60*60)
+	}
+
+	serverNow := now.In(location)
+	return time.Date(serverNow.Year(), serverNow.Month(), serverNow.Day()+1, 0, 0, 0, 0, location)
+}
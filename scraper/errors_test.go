@@ -2,10 +2,12 @@ package scraper
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestHandleResponse(t *testing.T) {
@@ -118,3 +120,72 @@ func TestHandleResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"ServerOverloaded", ServerOverloadedErr, true},
+		{"TooManyRequests", &retryAfterErr{err: TooManyRequestsErr, delay: time.Second}, true},
+		{"HTTPRequest", HTTPRequestErr, true},
+		{"ServerError", &statusCodeErr{code: http.StatusBadGateway}, true},
+		{"ClientError", &statusCodeErr{code: http.StatusTeapot}, false},
+		{"GameNotFound", GameNotFoundErr, false},
+		{"ScrapeQuota", ScrapeQuotaErr, false},
+		{"Aborted", errors.Join(UnreadableBodyErr, HTTPRequestAbortedErr), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if IsRetryable(tt.err) != tt.expected {
+				t.Errorf("expected IsRetryable(%v) to be %v", tt.err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsFatal(t *testing.T) {
+	for _, err := range []error{ScrapeQuotaErr, AppHasBeenBlockedErr, DevLoginErr, ToowManyUnknownRomsErr} {
+		if !IsFatal(fmt.Errorf("wrapped: %w", err)) {
+			t.Errorf("expected %v to be fatal", err)
+		}
+	}
+
+	for _, err := range []error{GameNotFoundErr, TooManyRequestsErr, HTTPRequestErr} {
+		if IsFatal(err) {
+			t.Errorf("expected %v not to be fatal", err)
+		}
+	}
+}
+
+func TestHandleResponseRetryAfter(t *testing.T) {
+	res := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"120"}},
+		Body:       io.NopCloser(strings.NewReader("Too Many Requests")),
+	}
+
+	_, err := handleResponse(res)
+
+	var retryAfter *retryAfterErr
+	if !errors.As(err, &retryAfter) || retryAfter.delay != 2*time.Minute {
+		t.Fatalf("expected a 2m retry after delay, got %v", err)
+	}
+	if !errors.Is(err, TooManyRequestsErr) {
+		t.Errorf("expected too many requests error, got %v", err)
+	}
+}
+
+func TestQuotaResetTime(t *testing.T) {
+	now := time.Date(2024, 3, 10, 22, 30, 0, 0, time.UTC)
+	reset := QuotaResetTime(now)
+
+	if !reset.After(now) || reset.Sub(now) > 24*time.Hour {
+		t.Errorf("expected reset within the next day, got %s", reset)
+	}
+	if reset.Hour() != 0 || reset.Minute() != 0 {
+		t.Errorf("expected reset at midnight server time, got %s", reset)
+	}
+}
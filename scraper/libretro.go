@@ -0,0 +1,184 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+const LibretroProvider = "libretro"
+
+var (
+	libretroThumbnailDirs = []struct {
+		dir       string
+		mediaType MediaType
+	}{
+		{"Named_Boxarts", Box2D},
+		{"Named_Snaps", Screenshot},
+		{"Named_Titles", TitleScreen},
+	}
+	libretroForbiddenChars = regexp.MustCompile("[&*/:`<>?\\\\|\"]")
+	libretroTags           = regexp.MustCompile(`\s*\[[^\[\]]*\]`)
+)
+
+// Libretro serves media from a libretro-thumbnails mirror, either a local
+// directory tree or a plain HTTP base URL. The system id is the libretro
+// playlist name, e.g. "Nintendo - Super Nintendo Entertainment System".
+type Libretro struct{}
+
+func (Libretro) IdentifyGame(ctx context.Context, systemID string, romPath string) (Game, error) {
+	names := libretroThumbnailNames(romPath)
+	if len(names) == 0 {
+		return Game{}, GameNotFoundErr
+	}
+	game := Game{Name: names[0]}
+
+	for _, thumbnails := range libretroThumbnailDirs {
+		for _, name := range names {
+			location, found, err := findLibretroThumbnail(ctx, systemID, thumbnails.dir, name+".png")
+			if err != nil {
+				return Game{}, err
+			}
+			if found {
+				game.Medias = append(game.Medias, Media{
+					Type:   string(thumbnails.mediaType),
+					URL:    location,
+					Format: "png",
+				})
+				break
+			}
+		}
+	}
+
+	if len(game.Medias) == 0 {
+		return Game{}, GameNotFoundErr
+	}
+
+	return game, nil
+}
+
+func (Libretro) ListMedia(_ context.Context, game Game) ([]Media, error) {
+	return game.Medias, nil
+}
+
+func (Libretro) FetchMedia(ctx context.Context, media Media, dest string) error {
+	if !isRemoteSource(media.URL) {
+		file, err := os.Open(media.URL)
+		if err != nil {
+			return fmt.Errorf("failed to read thumbnail: %w", err)
+		}
+		defer file.Close()
+
+		if err := saveMedia(dest, media, file, false); err != nil {
+			return fmt.Errorf("%s: %w", media.URL, err)
+		}
+		return nil
+	}
+
+	_, err := withRetry(ctx, func() (struct{}, error) {
+		return struct{}{}, download(ctx, media.URL, func(body io.Reader) error {
+			return saveMedia(dest, media, body, false)
+		})
+	})
+	return err
+}
+
+// libretroThumbnailNames returns the thumbnail names to try for a rom, from the
+// most to the least specific, with libretro's character substitutions applied.
+func libretroThumbnailNames(romPath string) []string {
+	fileName := filepath.Base(romPath)
+	label := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+
+	candidates := []string{
+		label,
+		cleanSpaces(libretroTags.ReplaceAllString(label, "")),
+	}
+	if i := strings.Index(label, " ("); i > 0 {
+		candidates = append(candidates, label[:i])
+	}
+
+	var names []string
+	for _, candidate := range candidates {
+		name := LibretroThumbnailName(candidate)
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// LibretroThumbnailName returns the file name, without extension, RetroArch
+// looks the thumbnails of a playlist label up with.
+func LibretroThumbnailName(label string) string {
+	return libretroForbiddenChars.ReplaceAllString(label, "_")
+}
+
+func findLibretroThumbnail(ctx context.Context, systemName, dir, fileName string) (string, bool, error) {
+	source := config.LibretroSource
+
+	if isRemoteSource(source) {
+		location, err := url.JoinPath(source, systemName, dir, fileName)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to build thumbnail URL: %w", err)
+		}
+		found, err := remoteFileExists(ctx, location)
+		return location, found, err
+	}
+
+	// Clones of the libretro-thumbnails repositories use underscores instead of spaces
+	for _, systemDir := range []string{systemName, strings.ReplaceAll(systemName, " ", "_")} {
+		location := filepath.Join(source, systemDir, dir, fileName)
+		if _, err := os.Stat(location); err == nil {
+			return location, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// remoteFileExists checks a thumbnail of the mirror. Only a 404 means it is
+// missing, the requests a busy or failing mirror refuses are tried again.
+func remoteFileExists(ctx context.Context, location string) (bool, error) {
+	return withRetry(ctx, func() (bool, error) {
+		return headRemoteFile(ctx, location)
+	})
+}
+
+func headRemoteFile(ctx context.Context, location string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, location, nil)
+	if err != nil {
+		return false, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, HTTPRequestAbortedErr
+		}
+		return false, HTTPRequestErr
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	case http.StatusTooManyRequests:
+		return false, withRetryAfter(res, TooManyRequestsErr)
+	}
+	return false, withRetryAfter(res, &statusCodeErr{code: res.StatusCode})
+}
+
+func isRemoteSource(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
@@ -0,0 +1,143 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+func TestLibretroThumbnailNames(t *testing.T) {
+	tests := []struct {
+		rom      string
+		expected []string
+	}{
+		{
+			rom:      "/roms/SFC/Super Mario World (USA).sfc",
+			expected: []string{"Super Mario World (USA)", "Super Mario World"},
+		},
+		{
+			rom:      "Legend of Zelda, The - A Link to the Past (USA) [!].zip",
+			expected: []string{"Legend of Zelda, The - A Link to the Past (USA) [!]", "Legend of Zelda, The - A Link to the Past (USA)", "Legend of Zelda, The - A Link to the Past"},
+		},
+		{
+			rom:      "Pinball: Fantasies & Dreams?.gb",
+			expected: []string{"Pinball_ Fantasies _ Dreams_"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rom, func(t *testing.T) {
+			names := libretroThumbnailNames(tt.rom)
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestLibretroIdentifyGameLocalMirror(t *testing.T) {
+	source := t.TempDir()
+	boxarts := filepath.Join(source, "Nintendo_-_Game_Boy", "Named_Boxarts")
+	_ = os.MkdirAll(boxarts, 0755)
+	_ = os.WriteFile(filepath.Join(boxarts, "Tetris.png"), []byte("\x89PNG\r\n\x1a\n"), 0644)
+
+	config.LibretroSource = source
+
+	game, err := (Libretro{}).IdentifyGame(context.Background(), "Nintendo - Game Boy", "Tetris (World) (Rev 1).gb")
+	if err != nil {
+		t.Fatalf("did not expect error but got: %v", err)
+	}
+
+	if len(game.Medias) != 1 || game.Medias[0].Type != string(Box2D) {
+		t.Fatalf("expected a single box-2D media, got %+v", game.Medias)
+	}
+
+	dest := filepath.Join(t.TempDir(), "Tetris.png")
+	if err := (Libretro{}).FetchMedia(context.Background(), game.Medias[0], dest); err != nil {
+		t.Fatalf("did not expect error but got: %v", err)
+	}
+
+	if _, err := (Libretro{}).IdentifyGame(context.Background(), "Nintendo - Game Boy", "Dr. Mario (World).gb"); !errors.Is(err, GameNotFoundErr) {
+		t.Errorf("expected game not found error, got %v", err)
+	}
+}
+
+func TestLibretroIdentifyGameRemote(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/Nintendo - Game Boy/Named_Snaps/Tetris (World).png" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	config.LibretroSource = server.URL
+
+	game, err := (Libretro{}).IdentifyGame(context.Background(), "Nintendo - Game Boy", "Tetris (World).gb")
+	if err != nil {
+		t.Fatalf("did not expect error but got: %v", err)
+	}
+
+	if len(game.Medias) != 1 || game.Medias[0].Type != string(Screenshot) {
+		t.Fatalf("expected a single ss media, got %+v", game.Medias)
+	}
+}
+
+func TestLibretroIdentifyGameRemoteRetries(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = time.Second }()
+	config.MaxAttempts = 3
+
+	tests := []struct {
+		name     string
+		failures []int
+		fails    bool
+	}{
+		{name: "Overloaded mirror", failures: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}},
+		{name: "Failing mirror", failures: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}, fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= len(tt.failures) {
+					w.WriteHeader(tt.failures[requests-1])
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			config.LibretroSource = server.URL
+
+			game, err := (Libretro{}).IdentifyGame(context.Background(), "Nintendo - Game Boy", "Tetris.gb")
+			if !tt.fails {
+				if err != nil {
+					t.Fatalf("did not expect error but got: %v", err)
+				}
+				if len(game.Medias) != len(libretroThumbnailDirs) {
+					t.Errorf("expected every thumbnail to be found, got %+v", game.Medias)
+				}
+				return
+			}
+
+			if errors.Is(err, GameNotFoundErr) || !errors.As(err, new(*statusCodeErr)) {
+				t.Errorf("expected the status error, got %v", err)
+			}
+			if requests != config.MaxAttempts {
+				t.Errorf("expected %d attempts, got %d", config.MaxAttempts, requests)
+			}
+		})
+	}
+}
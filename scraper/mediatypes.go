@@ -0,0 +1,151 @@
+package scraper
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+type MediaType string
+
+// MediaFormat is the kind of file a media type is served as.
+type MediaFormat string
+
+const (
+	ImageFormat    MediaFormat = "image"
+	VideoFormat    MediaFormat = "video"
+	DocumentFormat MediaFormat = "document"
+)
+
+const (
+	Box2D          MediaType = "box-2D"
+	Box2DBack      MediaType = "box-2D-back"
+	Box2DSide      MediaType = "box-2D-side"
+	Box3D          MediaType = "box-3D"
+	BoxTexture     MediaType = "box-texture"
+	MixV1          MediaType = "mixrbv1"
+	MixV2          MediaType = "mixrbv2"
+	Screenshot     MediaType = "ss"
+	TitleScreen    MediaType = "sstitle"
+	Wheel          MediaType = "wheel"
+	WheelHD        MediaType = "wheel-hd"
+	WheelCarbon    MediaType = "wheel-carbon"
+	WheelSteel     MediaType = "wheel-steel"
+	Marquee        MediaType = "screenmarquee"
+	MarqueeSmall   MediaType = "screenmarqueesmall"
+	Fanart         MediaType = "fanart"
+	Support2D      MediaType = "support-2D"
+	SupportTexture MediaType = "support-texture"
+	Video          MediaType = "video"
+	VideoNorm      MediaType = "video-normalized"
+	Manual         MediaType = "manuel"
+	// LocalMix is not served by providers, it's composed from other media
+	LocalMix MediaType = "mix"
+)
+
+type mediaTypeInfo struct {
+	format    MediaFormat
+	extension string
+}
+
+var (
+	mediaTypes = map[MediaType]mediaTypeInfo{
+		Box2D:          {ImageFormat, ".png"},
+		Box2DBack:      {ImageFormat, ".png"},
+		Box2DSide:      {ImageFormat, ".png"},
+		Box3D:          {ImageFormat, ".png"},
+		BoxTexture:     {ImageFormat, ".png"},
+		MixV1:          {ImageFormat, ".png"},
+		MixV2:          {ImageFormat, ".png"},
+		Screenshot:     {ImageFormat, ".png"},
+		TitleScreen:    {ImageFormat, ".png"},
+		Wheel:          {ImageFormat, ".png"},
+		WheelHD:        {ImageFormat, ".png"},
+		WheelCarbon:    {ImageFormat, ".png"},
+		WheelSteel:     {ImageFormat, ".png"},
+		Marquee:        {ImageFormat, ".png"},
+		MarqueeSmall:   {ImageFormat, ".png"},
+		Fanart:         {ImageFormat, ".jpg"},
+		Support2D:      {ImageFormat, ".png"},
+		SupportTexture: {ImageFormat, ".png"},
+		Video:          {VideoFormat, ".mp4"},
+		VideoNorm:      {VideoFormat, ".mp4"},
+		Manual:         {DocumentFormat, ".pdf"},
+		LocalMix:       {ImageFormat, ".png"},
+	}
+
+	// formatExtensions are the file extensions a media of each format may be
+	// saved with, when the provider tells which one it serves.
+	formatExtensions = map[MediaFormat][]string{
+		ImageFormat:    {".png", ".jpg", ".jpeg", ".gif", ".webp"},
+		VideoFormat:    {".mp4", ".webm", ".mkv", ".avi"},
+		DocumentFormat: {".pdf"},
+	}
+)
+
+// Format returns the kind of file the media type is served as.
+func (t MediaType) Format() MediaFormat {
+	return mediaTypes[t].format
+}
+
+// Extension returns the file extension media of this type are saved with by
+// default, e.g. ".png".
+func (t MediaType) Extension() string {
+	return mediaTypes[t].extension
+}
+
+// MediaTypeNames returns the names of every supported media type, sorted.
+func MediaTypeNames() []string {
+	names := make([]string, 0, len(mediaTypes))
+	for mediaType := range mediaTypes {
+		names = append(names, string(mediaType))
+	}
+	slices.Sort(names)
+	return names
+}
+
+func checkMediaType(mediaType MediaType) error {
+	if _, ok := mediaTypes[mediaType]; !ok {
+		return fmt.Errorf("%w %q, choose among %s", UnknownMediaTypeErr, mediaType, strings.Join(MediaTypeNames(), ", "))
+	}
+	return nil
+}
+
+// mediaExtension returns the extension the media is saved with: the one of
+// the format the provider serves when it fits the media type, changed to the
+// one images are processed to, if any.
+func mediaExtension(media Media) string {
+	mediaType := MediaType(media.Type)
+	ext := mediaType.Extension()
+	if media.Format != "" {
+		if served := "." + strings.ToLower(media.Format); slices.Contains(formatExtensions[mediaType.Format()], served) {
+			ext = served
+		}
+	}
+	if mediaType.Format() == ImageFormat {
+		return imageOptions(config.ScrapeMedia{}).OutputExtension(ext)
+	}
+	return ext
+}
+
+// MediaPath returns where the media is saved for a destination path given
+// without extension.
+func MediaPath(dest string, media Media) string {
+	return dest + mediaExtension(media)
+}
+
+// MediaExtensions returns the extensions media of the given types may be saved
+// with, to tell whether one of them was already scraped.
+func MediaExtensions(types []string) []string {
+	var extensions []string
+	for _, name := range types {
+		for _, ext := range formatExtensions[MediaType(name).Format()] {
+			if !slices.Contains(extensions, ext) {
+				extensions = append(extensions, ext)
+			}
+		}
+	}
+	return extensions
+}
@@ -0,0 +1,80 @@
+package scraper
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+func TestMediaPath(t *testing.T) {
+	tests := []struct {
+		media    Media
+		expected string
+	}{
+		{Media{Type: "box-3D"}, "Imgs/game.png"},
+		{Media{Type: "fanart"}, "Imgs/game.jpg"},
+		{Media{Type: "fanart", Format: "PNG"}, "Imgs/game.png"},
+		{Media{Type: "video", Format: "mp4"}, "Imgs/game.mp4"},
+		{Media{Type: "manuel", Format: "pdf"}, "Imgs/game.pdf"},
+		// A format that does not fit the type is ignored
+		{Media{Type: "ss", Format: "mp4"}, "Imgs/game.png"},
+	}
+
+	for _, tt := range tests {
+		if got := MediaPath("Imgs/game", tt.media); got != tt.expected {
+			t.Errorf("MediaPath(%+v) = %q, expected %q", tt.media, got, tt.expected)
+		}
+	}
+
+	// Processed WebP images are saved as PNG
+	originalBoxart := config.Boxart
+	defer func() { config.Boxart = originalBoxart }()
+	config.Boxart.Resize = "fit"
+	if got := MediaPath("Imgs/game", Media{Type: "box-2D", Format: "webp"}); got != "Imgs/game.png" {
+		t.Errorf("expected a processed WebP image to be saved as PNG, got %q", got)
+	}
+	config.Boxart.Resize = ""
+	if got := MediaPath("Imgs/game", Media{Type: "box-2D", Format: "webp"}); got != "Imgs/game.webp" {
+		t.Errorf("expected a WebP image to be kept, got %q", got)
+	}
+}
+
+func TestCheckMediaType(t *testing.T) {
+	for _, name := range MediaTypeNames() {
+		if err := checkMediaType(MediaType(name)); err != nil {
+			t.Errorf("expected %s to be supported, got %v", name, err)
+		}
+	}
+
+	if err := checkMediaType("box-4D"); !errors.Is(err, UnknownMediaTypeErr) {
+		t.Errorf("expected UnknownMediaTypeErr, got %v", err)
+	}
+}
+
+func TestMediaExtensions(t *testing.T) {
+	if got := MediaExtensions([]string{"box-3D", "manuel"}); !slices.Equal(got, []string{".png", ".jpg", ".jpeg", ".gif", ".webp", ".pdf"}) {
+		t.Errorf("unexpected extensions %v", got)
+	}
+}
+
+func TestAddWHToMediaURL(t *testing.T) {
+	tests := []struct {
+		media    Media
+		expected string
+	}{
+		{Media{Type: "box-2D", URL: "https://example.com/media?id=1", maxWidth: 250, maxHeight: 360}, "https://example.com/media?id=1&maxheight=360&maxwidth=250"},
+		{Media{Type: "video", URL: "https://example.com/media?id=1", maxWidth: 250, maxHeight: 360}, "https://example.com/media?id=1"},
+	}
+
+	for _, tt := range tests {
+		got, err := addWHToMediaURL(tt.media)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/anibaldeboni/screech/config"
+	"github.com/anibaldeboni/screech/imaging"
+	"github.com/anibaldeboni/screech/output"
+)
+
+// composeMix builds the mix image of a game from its separate media, laid out
+// by the template of the media entry, and saves it to dest. An existing file
+// is only replaced by a refresh.
+func composeMix(ctx context.Context, provider Provider, medias []Media, spec config.ScrapeMedia, dest string, replace *replacement) (Media, error) {
+	mix := Media{Type: string(LocalMix)}
+	dest = MediaPath(dest, mix)
+	if replace == nil {
+		if err := checkDestination(dest); err != nil {
+			return Media{}, err
+		}
+	}
+
+	layout := imaging.DefaultLayout
+	if spec.Layout != "" {
+		var err error
+		if layout, err = imaging.LoadLayout(config.ConfigPath(spec.Layout)); err != nil {
+			return Media{}, err
+		}
+	}
+
+	// The mix stands for the media of its layers, it changed if any of them did
+	key := mixKey(medias, spec, layout)
+	if replace != nil && replace.onlyChanged && key != "" && strings.EqualFold(mediaCRCs.lookup(replace.existing), key) {
+		return mix, MediaUnchangedErr
+	}
+
+	opts := imageOptions(spec)
+	layers, err := fetchLayers(ctx, provider, medias, spec, layout, opts.Width, opts.Height)
+	if err != nil {
+		return Media{}, err
+	}
+
+	img, err := imaging.Compose(layout, layers, opts.Width, opts.Height)
+	if err != nil {
+		return Media{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return Media{}, fmt.Errorf("failed to encode mix: %w", err)
+	}
+	if err := saveImage(dest, buf.Bytes(), spec); err != nil {
+		return Media{}, err
+	}
+	mediaCRCs.store(dest, key)
+	replace.replaced(dest)
+
+	return mix, nil
+}
+
+// mixKey joins the CRC of the media of every layer the game has, empty when
+// one of them has no CRC.
+func mixKey(medias []Media, spec config.ScrapeMedia, layout imaging.Layout) string {
+	var crcs []string
+	for _, layer := range layout.Layers {
+		media, ok := findLayerMedia(medias, MediaType(layer.Type), spec)
+		if !ok {
+			continue
+		}
+		if media.Crc == "" {
+			return ""
+		}
+		crcs = append(crcs, layer.Type+":"+media.Crc)
+	}
+	return strings.Join(crcs, ",")
+}
+
+// fetchLayers downloads the media of every layer of the layout the game has,
+// in the regions of the media entry.
+func fetchLayers(ctx context.Context, provider Provider, medias []Media, spec config.ScrapeMedia, layout imaging.Layout, width, height int) (map[string]image.Image, error) {
+	tmp, err := os.MkdirTemp("", "screech-mix")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mix directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	layers := make(map[string]image.Image)
+	for i, layer := range layout.Layers {
+		if _, ok := layers[layer.Type]; ok {
+			continue
+		}
+
+		media, ok := findLayerMedia(medias, MediaType(layer.Type), spec)
+		if !ok {
+			continue
+		}
+		media.maxWidth, media.maxHeight = int32(width), int32(height)
+
+		file := filepath.Join(tmp, strconv.Itoa(i))
+		if err := provider.FetchMedia(ctx, media, file); err != nil {
+			// An optional layer is left out, unless nothing more can be fetched
+			if layer.Required || errors.Is(err, HTTPRequestAbortedErr) || IsFatal(err) {
+				return nil, err
+			}
+			output.Printf("Skipping %s layer of the mix: %v\n", layer.Type, err)
+			continue
+		}
+
+		img, err := decodeImage(file)
+		if err != nil {
+			output.Printf("Skipping %s layer of the mix: %v\n", layer.Type, err)
+			continue
+		}
+		layers[layer.Type] = img
+	}
+
+	return layers, nil
+}
+
+func findLayerMedia(medias []Media, mediaType MediaType, spec config.ScrapeMedia) (Media, bool) {
+	mediasByType := filterMediasByType(medias, mediaType)
+	if media, ok := findMediaByRegion(mediasByType, spec.Regions); ok {
+		return media, true
+	}
+	if spec.IgnoreMissingRegion && len(mediasByType) > 0 {
+		return mediasByType[0], true
+	}
+	return Media{}, false
+}
+
+func decodeImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
+}
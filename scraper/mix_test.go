@@ -0,0 +1,127 @@
+package scraper
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+func writePNG(t *testing.T, path string, width, height int, c color.Color) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := range width {
+		for y := range height {
+			img.Set(x, y, c)
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownloadMediaLocalMix(t *testing.T) {
+	dir := t.TempDir()
+	writePNG(t, filepath.Join(dir, "ss.png"), 32, 24, color.RGBA{R: 255, A: 255})
+	writePNG(t, filepath.Join(dir, "box.png"), 20, 30, color.RGBA{B: 255, A: 255})
+
+	originalBoxart := config.Boxart
+	defer func() { config.Boxart = originalBoxart }()
+	config.Boxart.Width, config.Boxart.Height = 160, 120
+
+	game := Game{Medias: []Media{
+		{Type: "ss", URL: filepath.Join(dir, "ss.png"), Format: "png"},
+		{Type: "box-3D", URL: filepath.Join(dir, "box.png"), Region: "us", Format: "png"},
+		{Type: "box-2D", URL: filepath.Join(dir, "box.png"), Region: "us", Format: "png"},
+	}}
+
+	tests := []struct {
+		name     string
+		types    config.MediaTypes
+		expected MediaType
+	}{
+		{"mix only", config.MediaTypes{"mix"}, LocalMix},
+		{"mix before a provider type", config.MediaTypes{"mixrbv2", "mix", "box-2D"}, LocalMix},
+		{"provider type before the mix", config.MediaTypes{"box-2D", "mix"}, Box2D},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "game")
+			media, err := DownloadMedia(context.Background(), Libretro{}, game, config.ScrapeMedia{Type: tt.types, Regions: []string{"us"}}, dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if MediaType(media.Type) != tt.expected {
+				t.Fatalf("expected a %s, got %s", tt.expected, media.Type)
+			}
+
+			img, err := decodeImage(MediaPath(dest, media))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b := img.Bounds(); tt.expected == LocalMix && (b.Dx() != 160 || b.Dy() != 120) {
+				t.Errorf("expected a 160x120 mix, got %dx%d", b.Dx(), b.Dy())
+			}
+		})
+	}
+}
+
+func TestDownloadMediaLocalMixMissingLayers(t *testing.T) {
+	dir := t.TempDir()
+	writePNG(t, filepath.Join(dir, "box.png"), 20, 30, color.RGBA{B: 255, A: 255})
+	game := Game{Medias: []Media{{Type: "box-2D", URL: filepath.Join(dir, "box.png"), Format: "png"}}}
+
+	// The default layout requires a screenshot
+	dest := filepath.Join(t.TempDir(), "game")
+	if _, err := DownloadMedia(context.Background(), Libretro{}, game, config.ScrapeMedia{Type: config.MediaTypes{"mix"}}, dest); err == nil {
+		t.Error("expected an error without a screenshot")
+	}
+
+	media, err := DownloadMedia(context.Background(), Libretro{}, game, config.ScrapeMedia{Type: config.MediaTypes{"mix", "box-2D"}}, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if media.Type != "box-2D" {
+		t.Errorf("expected the fallback box-2D, got %s", media.Type)
+	}
+}
+
+func TestDownloadMediaLocalMixFailedLayers(t *testing.T) {
+	dir := t.TempDir()
+	writePNG(t, filepath.Join(dir, "ss.png"), 32, 24, color.RGBA{R: 255, A: 255})
+
+	originalBoxart := config.Boxart
+	defer func() { config.Boxart = originalBoxart }()
+	config.Boxart.Width, config.Boxart.Height = 160, 120
+
+	// The wheel can't be fetched, the mix is made without it
+	game := Game{Medias: []Media{
+		{Type: "ss", URL: filepath.Join(dir, "ss.png"), Format: "png"},
+		{Type: "wheel", URL: filepath.Join(dir, "missing.png"), Format: "png"},
+	}}
+	dest := filepath.Join(t.TempDir(), "game")
+	media, err := DownloadMedia(context.Background(), Libretro{}, game, config.ScrapeMedia{Type: config.MediaTypes{"mix"}}, dest)
+	if err != nil {
+		t.Fatalf("did not expect error but got: %v", err)
+	}
+	if MediaType(media.Type) != LocalMix {
+		t.Errorf("expected a mix, got %s", media.Type)
+	}
+
+	// The screenshot is required
+	game.Medias[0].URL = filepath.Join(dir, "missing.png")
+	dest = filepath.Join(t.TempDir(), "game")
+	if _, err := DownloadMedia(context.Background(), Libretro{}, game, config.ScrapeMedia{Type: config.MediaTypes{"mix"}}, dest); err == nil {
+		t.Error("expected an error when the required screenshot can't be fetched")
+	}
+}
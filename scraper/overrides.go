@@ -0,0 +1,131 @@
+package scraper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/anibaldeboni/screech/config"
+	"github.com/anibaldeboni/screech/output"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const MatchedByOverride = "override"
+
+// Override pins a rom to a ScreenScraper game or to a local image. In the
+// overrides file a plain value is read as the game id.
+type Override struct {
+	GameID string `yaml:"id"`
+	Image  string `yaml:"image"`
+}
+
+func (o *Override) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		o.GameID = value.Value
+		return nil
+	}
+
+	type plain Override
+	return value.Decode((*plain)(o))
+}
+
+// overridesFiles caches the parsed overrides files by path.
+var overridesFiles = struct {
+	mu    sync.Mutex
+	files map[string]map[string]Override
+}{files: make(map[string]map[string]Override)}
+
+// LookupOverride finds the override of a rom by file name or SHA1, looking in
+// the files in the given order. Relative images are resolved against the
+// directory of the overrides file.
+func LookupOverride(romPath string, files ...string) (Override, bool) {
+	var keys []string
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		file = config.ConfigPath(file)
+
+		overrides := loadOverrides(file)
+		if len(overrides) == 0 {
+			continue
+		}
+		if keys == nil {
+			keys = overrideKeys(romPath)
+		}
+
+		for _, key := range keys {
+			if override, ok := overrides[key]; ok {
+				if override.Image != "" && !filepath.IsAbs(override.Image) {
+					override.Image = filepath.Join(filepath.Dir(file), override.Image)
+				}
+				return override, true
+			}
+		}
+	}
+
+	return Override{}, false
+}
+
+func overrideKeys(romPath string) []string {
+	keys := []string{filepath.Base(romPath)}
+
+	hashes := romChecksums(romPath)
+	if hashes.SHA1 != "" {
+		keys = append(keys, hashes.SHA1)
+	}
+	if hashes.Archived != nil && hashes.Archived.SHA1 != "" {
+		keys = append(keys, hashes.Archived.SHA1)
+	}
+
+	return keys
+}
+
+func loadOverrides(file string) map[string]Override {
+	overridesFiles.mu.Lock()
+	defer overridesFiles.mu.Unlock()
+
+	if overrides, ok := overridesFiles.files[file]; ok {
+		return overrides
+	}
+
+	overrides := make(map[string]Override)
+	if data, err := os.ReadFile(file); err != nil {
+		output.Printf("Error reading overrides file: %v\n", err)
+	} else if err := yaml.Unmarshal(data, &overrides); err != nil {
+		output.Printf("Error parsing overrides file %s: %v\n", file, err)
+	}
+	overridesFiles.files[file] = overrides
+
+	return overrides
+}
+
+// UseOverrideImage copies the local image of an override to dest, given
+// without extension, post-processes it like a downloaded image of spec and
+// returns where it was saved. existing, when set, is the file of a previous
+// run it replaces, as in RefreshMedia.
+func UseOverrideImage(image string, spec config.ScrapeMedia, dest, existing string) (string, error) {
+	dest += imageOptions(spec).OutputExtension(strings.ToLower(filepath.Ext(image)))
+
+	if existing == "" {
+		if err := checkDestination(dest); err != nil {
+			return "", err
+		}
+	}
+
+	data, err := os.ReadFile(image)
+	if err != nil {
+		return "", fmt.Errorf("failed to read override image: %w", err)
+	}
+
+	if err := saveImage(dest, data, spec); err != nil {
+		return "", err
+	}
+	if existing != "" {
+		(&replacement{existing: existing}).replaced(dest)
+	}
+
+	return dest, nil
+}
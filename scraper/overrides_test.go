@@ -0,0 +1,104 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+func TestLookupOverride(t *testing.T) {
+	dir := t.TempDir()
+	originalConfigFile := config.ConfigFile
+	defer func() { config.ConfigFile = originalConfigFile }()
+	config.ConfigFile = filepath.Join(dir, "screech.yaml")
+	config.Cache.Dir = t.TempDir()
+
+	rom := filepath.Join(dir, "Cave Story (Homebrew).nes")
+	if err := os.WriteFile(rom, []byte("rom"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(dir, "Renamed.nes")
+	if err := os.WriteFile(other, []byte("rom"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	global := `
+"Cave Story (Homebrew).nes": "111"
+a1e17a20e93e5da710685444df3ad038ac66e2c9:
+  image: art/cave-story.png
+"Unknown.nes":
+  id: "333"
+`
+	system := `
+"Cave Story (Homebrew).nes":
+  id: "222"
+`
+	if err := os.WriteFile(filepath.Join(dir, "overrides.yaml"), []byte(global), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nes.yaml"), []byte(system), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		rom      string
+		files    []string
+		expected Override
+		found    bool
+	}{
+		{"by file name", rom, []string{"", "overrides.yaml"}, Override{GameID: "111"}, true},
+		{"system file first", rom, []string{"nes.yaml", "overrides.yaml"}, Override{GameID: "222"}, true},
+		{"by sha1", other, []string{"nes.yaml", "overrides.yaml"}, Override{Image: filepath.Join(dir, "art/cave-story.png")}, true},
+		{"missing file", rom, []string{"missing.yaml"}, Override{}, false},
+		{"no override", filepath.Join(dir, "Other.nes"), []string{"nes.yaml"}, Override{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			override, found := LookupOverride(tt.rom, tt.files...)
+			if found != tt.found || override != tt.expected {
+				t.Errorf("expected %+v (%v), got %+v (%v)", tt.expected, tt.found, override, found)
+			}
+		})
+	}
+}
+
+func TestUseOverrideImage(t *testing.T) {
+	dir := t.TempDir()
+	image := filepath.Join(dir, "image.png")
+	if err := os.WriteFile(image, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dest, err := UseOverrideImage(image, config.ScrapeMedia{}, filepath.Join(dir, "Imgs", "game"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dest != filepath.Join(dir, "Imgs", "game.png") {
+		t.Errorf("expected the image extension to be kept, got %s", dest)
+	}
+	if data, err := os.ReadFile(dest); err != nil || string(data) != "png" {
+		t.Errorf("expected the image to be copied, got %q (%v)", data, err)
+	}
+
+	if _, err := UseOverrideImage(image, config.ScrapeMedia{}, strings.TrimSuffix(dest, ".png"), ""); err == nil {
+		t.Error("expected an error when the destination exists")
+	}
+
+	// A refresh replaces the file of the previous run, whatever its extension
+	existing := filepath.Join(dir, "Imgs", "game.jpg")
+	_ = os.WriteFile(existing, []byte("jpg"), 0644)
+	if _, err := UseOverrideImage(image, config.ScrapeMedia{}, strings.TrimSuffix(dest, ".png"), existing); err != nil {
+		t.Fatalf("did not expect error but got: %v", err)
+	}
+	if _, err := os.Stat(existing); !os.IsNotExist(err) {
+		t.Error("expected the replaced file to be removed")
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "png" {
+		t.Errorf("expected the image to be copied again, got %q", data)
+	}
+}
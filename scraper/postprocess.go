@@ -0,0 +1,46 @@
+package scraper
+
+import (
+	"bytes"
+
+	"github.com/anibaldeboni/screech/config"
+	"github.com/anibaldeboni/screech/imaging"
+)
+
+// imageOptions returns how the images of a media entry are post-processed,
+// sized to the entry or, when it has no size, to the thumbnail config.
+func imageOptions(spec config.ScrapeMedia) imaging.Options {
+	opts := imaging.Options{
+		Width:   config.Boxart.Width,
+		Height:  config.Boxart.Height,
+		Resize:  imaging.Mode(config.Boxart.Resize),
+		Pad:     config.Boxart.Pad,
+		Format:  config.Boxart.Format,
+		Quality: config.Boxart.Quality,
+	}
+	if spec.Width > 0 {
+		opts.Width = int(spec.Width)
+	}
+	if spec.Height > 0 {
+		opts.Height = int(spec.Height)
+	}
+	return opts
+}
+
+// postProcess processes an image in place. It's given the temporary file an
+// image is saved to, so an image that can't be processed never takes the
+// place of dest, nor of the one a refresh replaces.
+func postProcess(tmp string, spec config.ScrapeMedia) error {
+	opts := imageOptions(spec)
+	if !opts.Enabled() {
+		return nil
+	}
+	return imaging.ProcessFile(tmp, opts)
+}
+
+// saveImage saves an image to dest once post-processed.
+func saveImage(dest string, data []byte, spec config.ScrapeMedia) error {
+	return writeStream(dest, bytes.NewReader(data), func(tmp string) error {
+		return postProcess(tmp, spec)
+	})
+}
@@ -0,0 +1,187 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/anibaldeboni/screech/atomicfile"
+	"github.com/anibaldeboni/screech/config"
+)
+
+const DefaultProvider = "screenscraper"
+
+var (
+	UnknownProviderErr = errors.New("unknown provider")
+
+	providers = map[string]Provider{
+		DefaultProvider:  ScreenScraper{},
+		LibretroProvider: Libretro{},
+	}
+)
+
+// Provider is a backend able to identify roms and serve their media.
+type Provider interface {
+	// IdentifyGame finds the game matching the rom file of the given system.
+	IdentifyGame(ctx context.Context, systemID string, romPath string) (Game, error)
+	// ListMedia returns every media the provider has for the game.
+	ListMedia(ctx context.Context, game Game) ([]Media, error)
+	// FetchMedia downloads the media and saves it to dest.
+	FetchMedia(ctx context.Context, media Media, dest string) error
+}
+
+// Game is the provider independent result of a rom identification.
+type Game struct {
+	ID     string
+	RomID  string
+	Name   string
+	Medias []Media
+	// MatchedBy tells how the game was identified, e.g. by hash or by a name search.
+	MatchedBy string
+	Metadata  Metadata
+}
+
+// Metadata is what frontends list about a game, when the provider has it.
+type Metadata struct {
+	// RegionName is the name of the game in the first of the preferred
+	// regions it has one for
+	RegionName string
+	// Names are the names of the game by region
+	Names       map[string]string
+	Description string
+	// ReleaseDate is formatted as YYYY-MM-DD, YYYY-MM or YYYY
+	ReleaseDate string
+	// Dates are the release dates by region
+	Dates     map[string]string
+	Developer string
+	Publisher string
+	// Genre joins the Genres for frontends with a single genre field
+	Genre   string
+	Genres  []string
+	Players string
+	// Rating goes from 0 to 1
+	Rating float64
+}
+
+// GetProvider returns the provider registered under name. An empty name
+// selects the default provider.
+func GetProvider(name string) (Provider, error) {
+	if name == "" {
+		name = DefaultProvider
+	}
+
+	provider, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", UnknownProviderErr, name)
+	}
+
+	return provider, nil
+}
+
+// DownloadMedia saves the game media matching one entry of the media config
+// and returns the media used. dest is given without extension, the one of the
+// media type found is appended to it.
+func DownloadMedia(ctx context.Context, provider Provider, game Game, spec config.ScrapeMedia, dest string) (Media, error) {
+	return downloadMedia(ctx, provider, game, spec, dest, nil)
+}
+
+// RefreshMedia downloads the media of an entry again over existing, the file
+// saved for it by a previous run. The new file takes its place only once
+// complete and existing is removed when the new one has another extension.
+// With onlyChanged existing is kept, and MediaUnchangedErr returned, when the
+// server media has the CRC recorded when existing was downloaded.
+func RefreshMedia(ctx context.Context, provider Provider, game Game, spec config.ScrapeMedia, dest, existing string, onlyChanged bool) (Media, error) {
+	return downloadMedia(ctx, provider, game, spec, dest, &replacement{existing: existing, onlyChanged: onlyChanged})
+}
+
+// FetchPreview saves the media of an entry to dest for a quick look, e.g. at
+// the candidates of an ambiguous match. Unlike DownloadMedia the file is
+// neither post-processed nor recorded in the media index.
+func FetchPreview(ctx context.Context, provider Provider, game Game, spec config.ScrapeMedia, dest string) error {
+	medias, err := provider.ListMedia(ctx, game)
+	if err != nil {
+		return err
+	}
+	media, err := findMedia(medias, spec)
+	if err != nil {
+		return err
+	}
+	return provider.FetchMedia(ctx, media, dest)
+}
+
+// replacement is the file of a previous run a download replaces.
+type replacement struct {
+	existing    string
+	onlyChanged bool
+}
+
+// replaced removes the file replaced by dest, unless dest was written over it.
+func (r *replacement) replaced(dest string) {
+	if r == nil || r.existing == dest {
+		return
+	}
+	os.Remove(r.existing)
+	mediaCRCs.store(r.existing, "")
+}
+
+func downloadMedia(ctx context.Context, provider Provider, game Game, spec config.ScrapeMedia, dest string, replace *replacement) (Media, error) {
+	if len(spec.Type) == 0 {
+		return Media{}, UnknownMediaTypeErr
+	}
+	for _, mediaType := range spec.Type {
+		if err := checkMediaType(MediaType(mediaType)); err != nil {
+			return Media{}, err
+		}
+	}
+
+	medias, err := provider.ListMedia(ctx, game)
+	if err != nil {
+		return Media{}, err
+	}
+
+	media, err := findMedia(medias, spec)
+
+	// The local mix is composed when it comes before the media found in the
+	// chain, if it can't be composed the media found is used instead
+	if mixAt := slices.Index(spec.Type, string(LocalMix)); mixAt >= 0 && (err != nil || slices.Index(spec.Type, media.Type) > mixAt) {
+		mix, mixErr := composeMix(ctx, provider, medias, spec, dest, replace)
+		if mixErr == nil || err != nil || errors.Is(mixErr, MediaUnchangedErr) {
+			return mix, mixErr
+		}
+	}
+	if err != nil {
+		return Media{}, err
+	}
+	media.maxWidth, media.maxHeight = spec.Width, spec.Height
+
+	dest = MediaPath(dest, media)
+	if replace == nil {
+		if err := checkDestination(dest); err != nil {
+			return Media{}, err
+		}
+	} else if replace.onlyChanged && media.Crc != "" && strings.EqualFold(mediaCRCs.lookup(replace.existing), media.Crc) {
+		return media, MediaUnchangedErr
+	}
+
+	if MediaType(media.Type).Format() == ImageFormat {
+		// Images are processed before they take the place of dest
+		err = atomicfile.Stage(dest, func(tmp string) error {
+			if err := provider.FetchMedia(ctx, media, tmp); err != nil {
+				return err
+			}
+			return postProcess(tmp, spec)
+		})
+	} else {
+		err = provider.FetchMedia(ctx, media, dest)
+	}
+	if err != nil {
+		return Media{}, err
+	}
+	mediaCRCs.store(dest, media.Crc)
+	replace.replaced(dest)
+
+	return media, nil
+}
@@ -0,0 +1,198 @@
+package scraper
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter enforces the limits ScreenScraper reports for the account in
+// the ssuser block: requests per minute, simultaneous requests and download
+// speed. It is shared by every worker and has no limits until seeded.
+type rateLimiter struct {
+	mu             sync.Mutex
+	seeded         bool
+	interval       time.Duration
+	maxThreads     int
+	bytesPerSecond int64
+	active         int
+	nextRequest    time.Time
+	nextByte       time.Time
+	released       chan struct{}
+}
+
+var limiter = newRateLimiter()
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{released: make(chan struct{})}
+}
+
+// seed sets the limits from the first server response, later calls are ignored.
+func (l *rateLimiter) seed(requestsPerMinute, maxThreads, maxDownloadSpeed string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.seeded {
+		return
+	}
+	l.seeded = true
+
+	if n, err := strconv.Atoi(requestsPerMinute); err == nil && n > 0 {
+		l.interval = time.Minute / time.Duration(n)
+	}
+	if n, err := strconv.Atoi(maxThreads); err == nil && n > 0 {
+		l.maxThreads = n
+	}
+	// The download speed is reported in KB/s
+	if n, err := strconv.ParseInt(maxDownloadSpeed, 10, 64); err == nil && n > 0 {
+		l.bytesPerSecond = n * 1024
+	}
+}
+
+// seedUser sets the limits from the ssuser block of a response.
+func (l *rateLimiter) seedUser(user UserInfo) {
+	l.seed(user.Maxrequestspermin, user.Maxthreads, user.Maxdownloadspeed)
+}
+
+// acquire waits for a free request slot and for the request rate to allow a
+// new request. The returned function must be called once the request is done.
+func (l *rateLimiter) acquire(ctx context.Context) (func(), error) {
+	for {
+		l.mu.Lock()
+		if l.maxThreads == 0 || l.active < l.maxThreads {
+			l.active++
+			now := time.Now()
+			start := now
+			if l.nextRequest.After(now) {
+				start = l.nextRequest
+			}
+			l.nextRequest = start.Add(l.interval)
+			l.mu.Unlock()
+
+			if err := sleep(ctx, start.Sub(now)); err != nil {
+				l.release()
+				return nil, err
+			}
+			return l.release, nil
+		}
+		released := l.released
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, HTTPRequestAbortedErr
+		case <-released:
+		}
+	}
+}
+
+func (l *rateLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active--
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// waitBytes delays the caller so that the bytes read by all workers stay
+// under the account download speed.
+func (l *rateLimiter) waitBytes(ctx context.Context, n int) error {
+	l.mu.Lock()
+	if l.bytesPerSecond == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	start := now
+	if l.nextByte.After(now) {
+		start = l.nextByte
+	}
+	l.nextByte = start.Add(time.Duration(int64(n) * int64(time.Second) / l.bytesPerSecond))
+	wait := l.nextByte.Sub(now)
+	l.mu.Unlock()
+
+	return sleep(ctx, wait)
+}
+
+func (l *rateLimiter) get(ctx context.Context, url string) ([]byte, error) {
+	return withRetry(ctx, func() ([]byte, error) {
+		return l.request(ctx, url)
+	})
+}
+
+func (l *rateLimiter) request(ctx context.Context, url string) ([]byte, error) {
+	release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	res, err := send(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	res.Body = io.NopCloser(&throttledReader{ctx: ctx, reader: res.Body, limiter: l})
+
+	return handleResponse(res)
+}
+
+// download streams a response body to fn within the limits, retrying like
+// get. fn is called again on a retry and must start over.
+func (l *rateLimiter) download(ctx context.Context, url string, fn func(io.Reader) error) error {
+	_, err := withRetry(ctx, func() (struct{}, error) {
+		return struct{}{}, l.downloadOnce(ctx, url, fn)
+	})
+	return err
+}
+
+func (l *rateLimiter) downloadOnce(ctx context.Context, url string, fn func(io.Reader) error) error {
+	release, err := l.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	res, err := send(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return readResponse(ctx, res, &throttledReader{ctx: ctx, reader: res.Body, limiter: l}, fn)
+}
+
+type throttledReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *rateLimiter
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	n, err := t.reader.Read(p)
+	if n > 0 {
+		if werr := t.limiter.waitBytes(t.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return HTTPRequestAbortedErr
+	case <-timer.C:
+		return nil
+	}
+}
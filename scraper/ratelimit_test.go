@@ -0,0 +1,94 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterMaxThreads(t *testing.T) {
+	l := newRateLimiter()
+	l.seed("", "2", "")
+
+	var active, peak atomic.Int32
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.acquire(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := active.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			active.Add(-1)
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() > 2 {
+		t.Errorf("expected at most 2 simultaneous requests, got %d", peak.Load())
+	}
+}
+
+func TestRateLimiterRequestsPerMinute(t *testing.T) {
+	l := newRateLimiter()
+	l.seed("1200", "", "") // one request every 50ms
+	l.seed("1", "1", "")   // only the first response seeds the limits
+
+	start := time.Now()
+	for range 3 {
+		release, err := l.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > time.Second {
+		t.Errorf("expected 3 requests to take about 100ms, took %s", elapsed)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	l := newRateLimiter()
+	l.seed("", "1", "")
+
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := l.acquire(ctx); !errors.Is(err, HTTPRequestAbortedErr) {
+		t.Errorf("expected request aborted error, got %v", err)
+	}
+}
+
+func TestRateLimiterDownloadSpeed(t *testing.T) {
+	l := newRateLimiter()
+	l.seed("", "", "10") // 10KB/s
+
+	start := time.Now()
+	_ = l.waitBytes(context.Background(), 512)
+	_ = l.waitBytes(context.Background(), 512)
+
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected 1KB at 10KB/s to take about 100ms, took %s", elapsed)
+	}
+}
@@ -0,0 +1,168 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anibaldeboni/screech/atomicfile"
+	"github.com/anibaldeboni/screech/config"
+)
+
+// Refresh modes, see config.Refresh. An empty mode is the same as none.
+const (
+	RefreshNone    = "none"
+	RefreshAll     = "all"
+	RefreshOlder   = "older"
+	RefreshSmaller = "smaller"
+	RefreshChanged = "changed"
+)
+
+const mediaIndexFile = "media.json"
+
+var (
+	UnknownRefreshModeErr = errors.New("unknown refresh mode")
+	MediaUnchangedErr     = errors.New("media unchanged on the server")
+
+	refreshModes = []string{"", RefreshNone, RefreshAll, RefreshOlder, RefreshSmaller, RefreshChanged}
+)
+
+// CheckRefreshMode returns an error for an unknown mode.
+func CheckRefreshMode(mode string) error {
+	if !slices.Contains(refreshModes, mode) {
+		return fmt.Errorf("%w: %s, expected one of %s", UnknownRefreshModeErr, mode, strings.Join(refreshModes[1:], ", "))
+	}
+	return nil
+}
+
+// NeedsRefresh tells whether a file scraped by a previous run is downloaded
+// again under the configured refresh mode. In the changed mode every file is,
+// RefreshMedia then keeps the ones the server has the same media for.
+func NeedsRefresh(path string, now time.Time) bool {
+	switch config.Refresh.Mode {
+	case RefreshAll, RefreshChanged:
+		return true
+	case RefreshOlder:
+		info, err := os.Stat(path)
+		return err == nil && info.ModTime().Before(now.AddDate(0, 0, -config.Refresh.MaxAge))
+	case RefreshSmaller:
+		return isSmallerImage(path, config.Refresh.MinWidth, config.Refresh.MinHeight)
+	default:
+		return false
+	}
+}
+
+// isSmallerImage reports whether the image is under the minimum size, or not
+// an image that can be read. Other media are never smaller.
+func isSmallerImage(path string, minWidth, minHeight int) bool {
+	if !slices.Contains(formatExtensions[ImageFormat], strings.ToLower(filepath.Ext(path))) {
+		return false
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return true
+	}
+	return cfg.Width < minWidth || cfg.Height < minHeight
+}
+
+// mediaIndex remembers the CRC the server advertised for each media file
+// downloaded, so the changed refresh mode can tell whether it was updated.
+type mediaIndex struct {
+	mu      sync.Mutex
+	entries map[string]string
+	loaded  bool
+	dirty   bool
+}
+
+var mediaCRCs = &mediaIndex{}
+
+func (i *mediaIndex) lookup(path string) string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.load()
+
+	return i.entries[mediaIndexKey(path)]
+}
+
+func (i *mediaIndex) store(path, crc string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.load()
+
+	key := mediaIndexKey(path)
+	if crc == "" {
+		if _, ok := i.entries[key]; ok {
+			delete(i.entries, key)
+			i.dirty = true
+		}
+		return
+	}
+	i.entries[key] = crc
+	i.dirty = true
+}
+
+func (i *mediaIndex) load() {
+	if i.loaded {
+		return
+	}
+	i.loaded = true
+	i.entries = make(map[string]string)
+
+	file, err := os.ReadFile(filepath.Join(config.CacheDir(), mediaIndexFile))
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(file, &i.entries)
+}
+
+func (i *mediaIndex) save() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if !i.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(i.entries)
+	if err != nil {
+		return err
+	}
+
+	if err := atomicfile.WriteFile(filepath.Join(config.CacheDir(), mediaIndexFile), data); err != nil {
+		return err
+	}
+	i.dirty = false
+
+	return nil
+}
+
+// mediaIndexKey is the absolute path of a media file, the same file is found
+// whatever the working dir.
+func mediaIndexKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// SaveMediaIndex persists the CRC of the media downloaded during the run.
+func SaveMediaIndex() error {
+	if err := mediaCRCs.save(); err != nil {
+		return fmt.Errorf("failed to save media index: %w", err)
+	}
+	return nil
+}
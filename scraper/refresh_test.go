@@ -0,0 +1,199 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+func TestCheckRefreshMode(t *testing.T) {
+	for _, mode := range []string{"", "none", "all", "older", "smaller", "changed"} {
+		if err := CheckRefreshMode(mode); err != nil {
+			t.Errorf("did not expect error for %q but got: %v", mode, err)
+		}
+	}
+	if err := CheckRefreshMode("newer"); !errors.Is(err, UnknownRefreshModeErr) {
+		t.Errorf("expected an unknown refresh mode error, got %v", err)
+	}
+}
+
+func TestNeedsRefresh(t *testing.T) {
+	originalRefresh := config.Refresh
+	defer func() { config.Refresh = originalRefresh }()
+
+	dir := t.TempDir()
+	now := time.Now()
+	small := filepath.Join(dir, "small.png")
+	writePNG(t, small, 100, 80, color.Black)
+	old := filepath.Join(dir, "old.png")
+	writePNG(t, old, 400, 300, color.Black)
+	_ = os.Chtimes(old, now.AddDate(0, 0, -40), now.AddDate(0, 0, -40))
+	broken := filepath.Join(dir, "broken.png")
+	_ = os.WriteFile(broken, []byte("<html>"), 0644)
+	video := filepath.Join(dir, "video.mp4")
+	_ = os.WriteFile(video, []byte("video"), 0644)
+
+	tests := []struct {
+		mode     string
+		path     string
+		expected bool
+	}{
+		{"", small, false},
+		{"none", old, false},
+		{"all", small, true},
+		{"changed", small, true},
+		{"older", old, true},
+		{"older", small, false},
+		{"smaller", small, true},
+		{"smaller", old, false},
+		{"smaller", broken, true},
+		{"smaller", video, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %s", tt.mode, filepath.Base(tt.path)), func(t *testing.T) {
+			config.Refresh.Mode = tt.mode
+			config.Refresh.MaxAge = 30
+			config.Refresh.MinWidth, config.Refresh.MinHeight = 200, 150
+
+			if refresh := NeedsRefresh(tt.path, now); refresh != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, refresh)
+			}
+		})
+	}
+}
+
+func fileCRC(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE(data))
+}
+
+func TestRefreshMedia(t *testing.T) {
+	originalIndex := mediaCRCs
+	defer func() { mediaCRCs = originalIndex }()
+
+	src := t.TempDir()
+	box := filepath.Join(src, "box.png")
+	writePNG(t, box, 20, 30, color.RGBA{B: 255, A: 255})
+	crc := fileCRC(t, box)
+	game := Game{Medias: []Media{{Type: "box-2D", URL: box, Format: "png", Crc: crc}}}
+	spec := config.ScrapeMedia{Type: config.MediaTypes{"box-2D"}}
+
+	tests := []struct {
+		name        string
+		existing    string
+		recorded    string
+		onlyChanged bool
+		expectErr   error
+	}{
+		{name: "Replaces the file", existing: "game.png"},
+		{name: "Replaces a file of another format", existing: "game.jpg"},
+		{name: "Keeps an unchanged file", existing: "game.png", recorded: crc, onlyChanged: true, expectErr: MediaUnchangedErr},
+		{name: "Replaces a changed file", existing: "game.png", recorded: "00000000", onlyChanged: true},
+		{name: "Replaces a file without a recorded CRC", existing: "game.png", onlyChanged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			existing := filepath.Join(dir, tt.existing)
+			_ = os.WriteFile(existing, []byte("previous run"), 0644)
+			mediaCRCs = &mediaIndex{loaded: true, entries: make(map[string]string)}
+			if tt.recorded != "" {
+				mediaCRCs.store(existing, tt.recorded)
+			}
+
+			_, err := RefreshMedia(context.Background(), Libretro{}, game, spec, filepath.Join(dir, "game"), existing, tt.onlyChanged)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectErr, err)
+			}
+
+			if tt.expectErr != nil {
+				if data, _ := os.ReadFile(existing); string(data) != "previous run" {
+					t.Errorf("expected the file to be kept, got %q", data)
+				}
+				return
+			}
+
+			dest := filepath.Join(dir, "game.png")
+			if fileCRC(t, dest) != crc {
+				t.Error("expected the file to be replaced")
+			}
+			if mediaCRCs.lookup(dest) != crc {
+				t.Errorf("expected the CRC to be recorded, got %q", mediaCRCs.lookup(dest))
+			}
+			if existing != dest {
+				if _, err := os.Stat(existing); !os.IsNotExist(err) {
+					t.Error("expected the file of the previous run to be removed")
+				}
+			}
+		})
+	}
+
+	// Without a refresh an existing file is never replaced
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "game.png"), []byte("previous run"), 0644)
+	if _, err := DownloadMedia(context.Background(), Libretro{}, game, spec, filepath.Join(dir, "game")); err == nil {
+		t.Error("expected an error when the destination exists")
+	}
+}
+
+func TestRefreshMediaPostProcessFailure(t *testing.T) {
+	originalIndex := mediaCRCs
+	originalBoxart := config.Boxart
+	defer func() {
+		mediaCRCs = originalIndex
+		config.Boxart = originalBoxart
+	}()
+	mediaCRCs = &mediaIndex{loaded: true, entries: make(map[string]string)}
+	config.Boxart.Width, config.Boxart.Height, config.Boxart.Resize = 120, 160, "fit"
+
+	// A PNG signature passes the download check but can't be decoded
+	box := filepath.Join(t.TempDir(), "box.png")
+	_ = os.WriteFile(box, []byte("\x89PNG\r\n\x1a\nbroken"), 0644)
+	game := Game{Medias: []Media{{Type: "box-2D", URL: box, Format: "png"}}}
+	spec := config.ScrapeMedia{Type: config.MediaTypes{"box-2D"}}
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "game.png")
+	_ = os.WriteFile(existing, []byte("previous run"), 0644)
+
+	if _, err := RefreshMedia(context.Background(), Libretro{}, game, spec, filepath.Join(dir, "game"), existing, false); err == nil {
+		t.Fatal("expected the post-processing to fail")
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "previous run" {
+		t.Errorf("expected the file to be kept, got %q", data)
+	}
+	assertDirFiles(t, dir, "game.png")
+}
+
+func TestFetchPreviewSkipsMediaIndex(t *testing.T) {
+	originalIndex := mediaCRCs
+	defer func() { mediaCRCs = originalIndex }()
+	mediaCRCs = &mediaIndex{loaded: true, entries: make(map[string]string)}
+
+	box := filepath.Join(t.TempDir(), "box.png")
+	writePNG(t, box, 20, 30, color.RGBA{B: 255, A: 255})
+	game := Game{Medias: []Media{{Type: "box-2D", URL: box, Format: "png", Crc: fileCRC(t, box)}}}
+
+	dest := filepath.Join(t.TempDir(), "42.png")
+	if err := FetchPreview(context.Background(), Libretro{}, game, config.ScrapeMedia{Type: config.MediaTypes{"box-2D"}}, dest); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dest); err != nil {
+		t.Errorf("expected the preview to be saved: %v", err)
+	}
+	if len(mediaCRCs.entries) != 0 {
+		t.Errorf("expected previews not to be recorded, got %v", mediaCRCs.entries)
+	}
+}
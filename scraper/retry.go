@@ -0,0 +1,44 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+var (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// withRetry calls fn until it succeeds, fails with an error that is not
+// retryable or the configured number of attempts is reached. Attempts are
+// spaced by an exponential backoff with jitter, or by the delay the server
+// asked for in Retry-After when it is longer.
+func withRetry[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := fn()
+		if err == nil || !IsRetryable(err) || attempt >= config.MaxAttempts {
+			return res, err
+		}
+
+		if err := sleep(ctx, retryDelay(attempt, err)); err != nil {
+			return res, err
+		}
+	}
+}
+
+func retryDelay(attempt int, err error) time.Duration {
+	backoff := min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+	delay := backoff/2 + rand.N(backoff/2+1)
+
+	var retryAfter *retryAfterErr
+	if errors.As(err, &retryAfter) && retryAfter.delay > delay {
+		delay = retryAfter.delay
+	}
+
+	return delay
+}
@@ -0,0 +1,100 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+func TestRetryTransientErrors(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = time.Second }()
+
+	tests := []struct {
+		name             string
+		failures         int
+		status           int
+		maxAttempts      int
+		expectedRequests int
+		expectErr        error
+	}{
+		{
+			name:             "Succeeds after overloaded server",
+			failures:         2,
+			status:           http.StatusTooManyRequests,
+			maxAttempts:      3,
+			expectedRequests: 3,
+		},
+		{
+			name:             "Gives up after max attempts",
+			failures:         5,
+			status:           http.StatusServiceUnavailable,
+			maxAttempts:      2,
+			expectedRequests: 2,
+			expectErr:        errors.New("unexpected status code: 503, response: "),
+		},
+		{
+			name:             "Does not retry fatal errors",
+			failures:         5,
+			status:           430,
+			maxAttempts:      3,
+			expectedRequests: 1,
+			expectErr:        ScrapeQuotaErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= tt.failures {
+					w.WriteHeader(tt.status)
+					return
+				}
+				_, _ = w.Write([]byte("OK"))
+			}))
+			defer server.Close()
+
+			config.MaxAttempts = tt.maxAttempts
+			res, err := newRateLimiter().get(context.Background(), server.URL)
+
+			if tt.expectErr == nil && (err != nil || string(res) != "OK") {
+				t.Errorf("expected OK, got %q, %v", res, err)
+			}
+			if tt.expectErr != nil && (err == nil || err.Error() != tt.expectErr.Error()) {
+				t.Errorf("expected error %v, got %v", tt.expectErr, err)
+			}
+			if requests != tt.expectedRequests {
+				t.Errorf("expected %d requests, got %d", tt.expectedRequests, requests)
+			}
+		})
+	}
+}
+
+func TestRetryHonorsCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	config.MaxAttempts = 3
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := newRateLimiter().get(ctx, server.URL)
+
+	if !errors.Is(err, HTTPRequestAbortedErr) {
+		t.Errorf("expected request aborted error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("expected retry to stop when the context is cancelled")
+	}
+}
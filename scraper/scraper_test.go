@@ -1,14 +1,19 @@
 package scraper_test
 
 import (
+	"archive/zip"
 	"context"
 	"encoding/json"
 	"errors"
+	"image"
+	_ "image/jpeg"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/anibaldeboni/screech/config"
 	"github.com/anibaldeboni/screech/scraper"
@@ -65,6 +70,73 @@ func TestFindGame(t *testing.T) {
 	}
 }
 
+func TestFindGameCachesResponse(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"response":{"jeu":{"id":"1234"}}}`))
+	}))
+	defer server.Close()
+
+	scraper.BaseURL = server.URL
+	config.Cache.Dir = t.TempDir()
+	config.Cache.TTL = time.Hour
+
+	rom := filepath.Join(t.TempDir(), "game.sfc")
+	_ = os.WriteFile(rom, []byte("rom"), 0644)
+
+	for range 2 {
+		res, err := scraper.FindGame(context.Background(), "4", rom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Response.Jeu.ID != "1234" {
+			t.Errorf("Expected game id 1234, got %s", res.Response.Jeu.ID)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d", requests)
+	}
+}
+
+func TestFindGameZippedRom(t *testing.T) {
+	var romNames []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		romNames = append(romNames, r.URL.Query().Get("romnom"))
+		if r.URL.Query().Get("crc") == "79520fa1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"response":{"jeu":{"id":"1234"}}}`))
+	}))
+	defer server.Close()
+
+	scraper.BaseURL = server.URL
+	config.Cache.Dir = t.TempDir()
+
+	rom := filepath.Join(t.TempDir(), "Game (USA).zip")
+	file, _ := os.Create(rom)
+	writer := zip.NewWriter(file)
+	w, _ := writer.Create("Game (USA).sfc")
+	_, _ = w.Write([]byte("rom"))
+	_ = writer.Close()
+	_ = file.Close()
+
+	res, err := scraper.FindGame(context.Background(), "4", rom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Response.Jeu.ID != "1234" {
+		t.Errorf("Expected game id 1234, got %s", res.Response.Jeu.ID)
+	}
+
+	expected := []string{"Game (USA).sfc", "Game.zip"}
+	if strings.Join(romNames, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected lookups %v, got %v", expected, romNames)
+	}
+}
+
 func TestFindGameCancelContext(t *testing.T) {
 	server := setupStubServer(t)
 	defer server.Close()
@@ -85,16 +157,16 @@ func TestDownloadMedia(t *testing.T) {
 	defer server.Close()
 
 	scraper.BaseURL = server.URL + "/get-media"
-	config.Media.Regions = []string{"br"}
-	err := scraper.DownloadMedia(
+	_, err := scraper.DownloadMedia(
 		context.Background(),
-		[]scraper.Media{
+		scraper.ScreenScraper{},
+		scraper.Game{Medias: []scraper.Media{
 			{
 				URL:    server.URL + "/get-media",
 				Type:   "box-3D",
 				Region: "br",
 			},
-		}, scraper.Box3D, "screenshot.png")
+		}}, config.ScrapeMedia{Type: config.MediaTypes{"box-3D"}, Regions: []string{"br"}}, "screenshot")
 
 	os.Remove("screenshot.png")
 
@@ -109,20 +181,20 @@ func TestDownloadMediaCancelContext(t *testing.T) {
 	defer server.Close()
 
 	scraper.BaseURL = server.URL + "/get-media"
-	config.Media.Regions = []string{"br"}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	err := scraper.DownloadMedia(
+	_, err := scraper.DownloadMedia(
 		ctx,
-		[]scraper.Media{
+		scraper.ScreenScraper{},
+		scraper.Game{Medias: []scraper.Media{
 			{
 				URL:    server.URL + "/get-media",
 				Type:   "box-3D",
 				Region: "br",
 			},
-		}, scraper.Box3D, "screenshot.png")
+		}}, config.ScrapeMedia{Type: config.MediaTypes{"box-3D"}, Regions: []string{"br"}}, "screenshot")
 
 	if !errors.Is(err, scraper.HTTPRequestAbortedErr) {
 		t.Errorf("Expected HTTP Request Aborted error, got %v", err)
@@ -135,16 +207,16 @@ func TestDownloadMediaInvalidRegion(t *testing.T) {
 	defer server.Close()
 
 	scraper.BaseURL = server.URL + "/get-media"
-	config.Media.Regions = []string{"ar"}
-	err := scraper.DownloadMedia(
+	_, err := scraper.DownloadMedia(
 		context.Background(),
-		[]scraper.Media{
+		scraper.ScreenScraper{},
+		scraper.Game{Medias: []scraper.Media{
 			{
 				URL:    server.URL + "/get-media",
 				Type:   "box-3D",
 				Region: "br",
 			},
-		}, scraper.Box3D, "screenshot.png")
+		}}, config.ScrapeMedia{Type: config.MediaTypes{"box-3D"}, Regions: []string{"ar"}}, "screenshot")
 
 	if err == nil {
 		t.Error("Expected error, got nil")
@@ -161,17 +233,16 @@ func TestDownloadMediaIgnoringMissingRegion(t *testing.T) {
 	defer server.Close()
 
 	scraper.BaseURL = server.URL + "/get-media"
-	config.Media.Regions = []string{"ar"}
-	config.Media.IgnoreMissingRegion = true
-	err := scraper.DownloadMedia(
+	_, err := scraper.DownloadMedia(
 		context.Background(),
-		[]scraper.Media{
+		scraper.ScreenScraper{},
+		scraper.Game{Medias: []scraper.Media{
 			{
 				URL:    server.URL + "/get-media",
 				Type:   "box-3D",
 				Region: "br",
 			},
-		}, scraper.Box3D, "screenshot.png")
+		}}, config.ScrapeMedia{Type: config.MediaTypes{"box-3D"}, Regions: []string{"ar"}, IgnoreMissingRegion: true}, "screenshot")
 
 	os.Remove("screenshot.png")
 
@@ -180,22 +251,68 @@ func TestDownloadMediaIgnoringMissingRegion(t *testing.T) {
 	}
 }
 
+func TestDownloadMediaPostProcess(t *testing.T) {
+	server := setupStubServer(t)
+	defer server.Close()
+
+	originalBoxart := config.Boxart
+	defer func() { config.Boxart = originalBoxart }()
+	config.Boxart.Width, config.Boxart.Height = 120, 160
+	config.Boxart.Resize, config.Boxart.Pad = "fit", true
+	config.Boxart.Format, config.Boxart.Quality = "jpeg", 80
+
+	dest := filepath.Join(t.TempDir(), "game")
+	media, err := scraper.DownloadMedia(
+		context.Background(),
+		scraper.ScreenScraper{},
+		scraper.Game{Medias: []scraper.Media{
+			{
+				URL:    server.URL + "/get-media",
+				Type:   "box-3D",
+				Region: "br",
+				Format: "png",
+			},
+		}}, config.ScrapeMedia{Type: config.MediaTypes{"box-3D"}, Regions: []string{"br"}}, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := scraper.MediaPath(dest, media)
+	if path != dest+".jpg" {
+		t.Errorf("expected the image to be saved as jpeg, got %s", path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	img, format, err := image.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); format != "jpeg" || b.Dx() != 120 || b.Dy() != 160 {
+		t.Errorf("expected a 120x160 jpeg, got a %dx%d %s", b.Dx(), b.Dy(), format)
+	}
+}
+
 func TestDownloadMediaInvalidMediaType(t *testing.T) {
 	server := setupStubServer(t)
 
 	defer server.Close()
 
 	scraper.BaseURL = server.URL + "/get-media"
-	config.Media.Regions = []string{"br"}
-	err := scraper.DownloadMedia(
+	_, err := scraper.DownloadMedia(
 		context.Background(),
-		[]scraper.Media{
+		scraper.ScreenScraper{},
+		scraper.Game{Medias: []scraper.Media{
 			{
 				URL:    server.URL + "/get-media",
 				Type:   "box-3D",
 				Region: "br",
 			},
-		}, scraper.MediaType("invalid-media"), "screenshot.png")
+		}}, config.ScrapeMedia{Type: config.MediaTypes{"invalid-media"}, Regions: []string{"br"}}, "screenshot")
 
 	if err == nil {
 		t.Error("Expected error, got nil")
@@ -205,3 +322,57 @@ func TestDownloadMediaInvalidMediaType(t *testing.T) {
 		t.Errorf("Expected Unknown Media Type error, got %v", err)
 	}
 }
+
+func TestGetProvider(t *testing.T) {
+	if _, err := scraper.GetProvider(""); err != nil {
+		t.Errorf("Expected default provider, got %v", err)
+	}
+
+	if _, err := scraper.GetProvider("invalid-provider"); !errors.Is(err, scraper.UnknownProviderErr) {
+		t.Errorf("Expected Unknown Provider error, got %v", err)
+	}
+}
+
+func TestFetchUserInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantID   string
+		expected error
+	}{
+		{
+			name:   "valid credentials",
+			body:   `{"response":{"ssuser":{"id":"player","requeststoday":"12","maxrequestsperday":"20000"}}}`,
+			wantID: "player",
+		},
+		{
+			name:     "invalid credentials",
+			body:     `{"response":{"serveurs":{}}}`,
+			expected: scraper.InvalidCredentialsErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("ssid") != "player" {
+					t.Errorf("Expected ssid player, got %s", r.URL.Query().Get("ssid"))
+				}
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			scraper.UserInfoURL = server.URL
+			config.Username = "player"
+			defer func() { config.Username = "" }()
+
+			user, err := scraper.FetchUserInfo(context.Background())
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected error %v, got %v", tt.expected, err)
+			}
+			if user.ID != tt.wantID {
+				t.Errorf("Expected user %q, got %q", tt.wantID, user.ID)
+			}
+		})
+	}
+}
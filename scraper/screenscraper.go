@@ -0,0 +1,171 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+// ScreenScraper is the screenscraper.fr provider.
+type ScreenScraper struct{}
+
+func (ScreenScraper) IdentifyGame(ctx context.Context, systemID string, romPath string) (Game, error) {
+	if gameID, ok := chosenGame(systemID, romPath); ok {
+		game, err := GameByID(ctx, systemID, gameID)
+		if err != nil {
+			return Game{}, err
+		}
+		game.MatchedBy = MatchedByChoice
+		return game, nil
+	}
+
+	res, err := FindGame(ctx, systemID, romPath)
+	if err == nil {
+		game := res.Response.Jeu.toGame()
+		game.MatchedBy = MatchedByHash
+		return game, nil
+	}
+	if !errors.Is(err, GameNotFoundErr) {
+		return Game{}, err
+	}
+
+	game, err := SearchGame(ctx, systemID, romPath)
+	if err != nil {
+		return Game{}, err
+	}
+	game.MatchedBy = MatchedBySearch
+
+	return game, nil
+}
+
+func (ScreenScraper) ListMedia(_ context.Context, game Game) ([]Media, error) {
+	return game.Medias, nil
+}
+
+func (ScreenScraper) FetchMedia(ctx context.Context, media Media, dest string) error {
+	mediaURL, err := addWHToMediaURL(media)
+	if err != nil {
+		return err
+	}
+
+	// Images are always asked with a maximum size, the server may have resized
+	// them, then they can't match the advertised checksums
+	resizable := MediaType(media.Type).Format() == ImageFormat
+
+	return limiter.download(ctx, mediaURL, func(body io.Reader) error {
+		return saveMedia(dest, media, body, resizable)
+	})
+}
+
+func (jeu Jeu) toGame() Game {
+	game := Game{
+		ID:       jeu.ID,
+		RomID:    jeu.Romid,
+		Medias:   jeu.Medias,
+		Metadata: jeu.metadata(),
+	}
+	if len(jeu.Noms) > 0 {
+		game.Name = jeu.Noms[0].Text
+	}
+
+	return game
+}
+
+// metadata picks the texts in the preferred languages and the release date of the preferred
+// regions, falling back to the first ones.
+func (jeu Jeu) metadata() Metadata {
+	meta := Metadata{
+		Developer: jeu.Developpeur.Text,
+		Publisher: jeu.Editeur.Text,
+		Players:   jeu.Joueurs.Text,
+	}
+
+	if len(jeu.Noms) > 0 {
+		names := make(map[string]string, len(jeu.Noms))
+		for _, nom := range jeu.Noms {
+			names[nom.Region] = nom.Text
+		}
+		// Without a name in the preferred regions the first, default, one is used
+		meta.RegionName = byPreference(names, slices.Concat(config.PreferredRegions(), []string{jeu.Noms[0].Region})...)
+		meta.Names = names
+	}
+
+	synopsis := make(map[string]string, len(jeu.Synopsis))
+	for _, s := range jeu.Synopsis {
+		synopsis[s.Langue] = s.Text
+	}
+	meta.Description = byPreference(synopsis, metadataLanguages()...)
+
+	if len(jeu.Dates) > 0 {
+		dates := make(map[string]string, len(jeu.Dates))
+		for _, d := range jeu.Dates {
+			dates[d.Region] = d.Text
+		}
+		meta.ReleaseDate = byPreference(dates, config.PreferredRegions()...)
+		meta.Dates = dates
+	}
+
+	for _, genre := range jeu.Genres {
+		names := make(map[string]string, len(genre.Noms))
+		for _, nom := range genre.Noms {
+			names[nom.Langue] = nom.Text
+		}
+		if name := byPreference(names, metadataLanguages()...); name != "" {
+			meta.Genres = append(meta.Genres, name)
+		}
+	}
+	meta.Genre = strings.Join(meta.Genres, " / ")
+
+	// Ratings are out of 20
+	if note, err := strconv.ParseFloat(jeu.Note.Text, 64); err == nil {
+		meta.Rating = min(max(note/20, 0), 1)
+	}
+
+	return meta
+}
+
+// metadataLanguages returns the configured languages of the descriptions and
+// genres, English is the fallback since most games have their texts in it.
+func metadataLanguages() []string {
+	return slices.Concat(config.Languages, []string{"en"})
+}
+
+// byPreference returns the value of the first preferred key found, or the
+// value of the smallest key so the choice is stable.
+func byPreference(values map[string]string, preferred ...string) string {
+	for _, key := range preferred {
+		if value, ok := values[key]; ok {
+			return value
+		}
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	slices.Sort(keys)
+	return values[keys[0]]
+}
+
+func (jeu Jeu) toCandidate(confidence float64) Candidate {
+	candidate := Candidate{
+		Game:       jeu.toGame(),
+		Publisher:  jeu.Editeur.Text,
+		Confidence: confidence,
+	}
+	if len(jeu.Noms) > 0 {
+		candidate.Region = jeu.Noms[0].Region
+	}
+	if len(jeu.Dates) > 0 {
+		candidate.Year, _, _ = strings.Cut(jeu.Dates[0].Text, "-")
+	}
+
+	return candidate
+}
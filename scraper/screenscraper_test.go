@@ -0,0 +1,74 @@
+package scraper
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+func TestJeuMetadata(t *testing.T) {
+	config.Media = config.MediaList{{Regions: []string{"eu", "us"}}}
+
+	var jeu Jeu
+	err := json.Unmarshal([]byte(`{
+		"noms": [{"region": "ss", "text": "Super Mario World"}, {"region": "jp", "text": "Super Mario World: Super Mario Bros. 4"}, {"region": "us", "text": "Super Mario World (US)"}],
+		"editeur": {"text": "Nintendo"},
+		"developpeur": {"text": "Nintendo EAD"},
+		"joueurs": {"text": "1-2"},
+		"note": {"text": "18"},
+		"synopsis": [{"langue": "fr", "text": "Mario est de retour"}, {"langue": "en", "text": "Mario is back"}],
+		"dates": [{"region": "jp", "text": "1990-11-21"}, {"region": "us", "text": "1991-08-23"}],
+		"genres": [
+			{"noms": [{"langue": "de", "text": "Plattform"}, {"langue": "en", "text": "Platform"}]},
+			{"noms": [{"langue": "fr", "text": "Action"}]}
+		]
+	}`), &jeu)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Metadata{
+		RegionName: "Super Mario World (US)",
+		Names: map[string]string{
+			"ss": "Super Mario World",
+			"jp": "Super Mario World: Super Mario Bros. 4",
+			"us": "Super Mario World (US)",
+		},
+		Description: "Mario is back",
+		ReleaseDate: "1991-08-23",
+		Dates:       map[string]string{"jp": "1990-11-21", "us": "1991-08-23"},
+		Developer:   "Nintendo EAD",
+		Publisher:   "Nintendo",
+		Genre:       "Platform / Action",
+		Genres:      []string{"Platform", "Action"},
+		Players:     "1-2",
+		Rating:      0.9,
+	}
+	if meta := jeu.metadata(); !reflect.DeepEqual(meta, expected) {
+		t.Errorf("expected %+v, got %+v", expected, meta)
+	}
+
+	// Without a name in the preferred regions the default one is kept
+	config.Media = config.MediaList{{Regions: []string{"br"}}}
+	if meta := jeu.metadata(); meta.RegionName != "Super Mario World" {
+		t.Errorf("expected the default name, got %q", meta.RegionName)
+	}
+
+	// Texts follow the preferred languages, falling back to English
+	originalLanguages := config.Languages
+	defer func() { config.Languages = originalLanguages }()
+	config.Languages = []string{"pt", "de"}
+	if meta := jeu.metadata(); meta.Description != "Mario is back" || meta.Genre != "Plattform / Action" {
+		t.Errorf("expected the German genre and the English synopsis, got %q and %q", meta.Genre, meta.Description)
+	}
+	config.Languages = []string{"fr"}
+	if meta := jeu.metadata(); meta.Description != "Mario est de retour" {
+		t.Errorf("expected the French synopsis, got %q", meta.Description)
+	}
+
+	if meta := (Jeu{}).metadata(); !reflect.DeepEqual(meta, Metadata{}) {
+		t.Errorf("expected no metadata, got %+v", meta)
+	}
+}
@@ -0,0 +1,233 @@
+package scraper
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+const (
+	MatchedByHash   = "hash"
+	MatchedBySearch = "search"
+	MatchedByChoice = "choice"
+
+	nameWeight   = 0.7
+	systemWeight = 0.2
+	regionWeight = 0.1
+
+	maxCandidates = 10
+	// Results whose names are further from the rom name are not worth offering
+	minNameSimilarity = 0.5
+	// Two candidates closer than this are too close to call
+	ambiguityMargin = 0.05
+)
+
+var (
+	SearchURL = "https://www.screenscraper.fr/api2/jeuRecherche.php"
+
+	romTagsRegex = regexp.MustCompile(`\(([^()]*)\)`)
+	regionTags   = map[string]string{
+		"usa":       "us",
+		"europe":    "eu",
+		"japan":     "jp",
+		"world":     "wor",
+		"brazil":    "br",
+		"france":    "fr",
+		"germany":   "de",
+		"spain":     "sp",
+		"italy":     "it",
+		"korea":     "kr",
+		"australia": "au",
+		"asia":      "asi",
+		"china":     "cn",
+	}
+)
+
+// Candidate is a game a name search could not rule out for a rom.
+type Candidate struct {
+	Game
+	Year       string
+	Publisher  string
+	Region     string
+	Confidence float64
+}
+
+// AmbiguousMatchError is returned when a name search finds candidates but none
+// of them stands out, so the user can pick the right one.
+type AmbiguousMatchError struct {
+	Rom        string
+	Candidates []Candidate
+}
+
+func (e *AmbiguousMatchError) Error() string {
+	return fmt.Sprintf("%d possible games found for %s", len(e.Candidates), e.Rom)
+}
+
+func (e *AmbiguousMatchError) Unwrap() error {
+	return GameNotFoundErr
+}
+
+// SearchGame looks the rom up by its cleaned name and returns the best
+// candidate when it scores at least the configured confidence and clearly
+// beats the others. Otherwise the candidates are returned in an
+// AmbiguousMatchError.
+func SearchGame(ctx context.Context, systemID string, romPath string) (Game, error) {
+	name := cleanRomName(romPath)
+	if config.Search.Disabled || name == "" {
+		return Game{}, GameNotFoundErr
+	}
+
+	result, err := searchGames(ctx, systemID, name)
+	if err != nil {
+		return Game{}, err
+	}
+
+	candidates := rankCandidates(result.Response.Jeux, name, systemID, romRegions(romPath))
+	if len(candidates) == 0 {
+		return Game{}, fmt.Errorf("%w: no search result for %q", GameNotFoundErr, name)
+	}
+
+	best := candidates[0]
+	if best.Confidence >= config.Search.MinConfidence &&
+		(len(candidates) == 1 || best.Confidence-candidates[1].Confidence > ambiguityMargin) {
+		return best.Game, nil
+	}
+
+	return Game{}, &AmbiguousMatchError{Rom: name, Candidates: candidates[:min(len(candidates), maxCandidates)]}
+}
+
+func searchGames(ctx context.Context, systemID, name string) (GameSearchResponse, error) {
+	var result GameSearchResponse
+
+	key := searchCacheKey(systemID, name)
+	if res, ok := cachedResponse(key); ok {
+		if err := json.Unmarshal(res, &result); err == nil {
+			limiter.seedUser(result.Response.Ssuser)
+			return result, nil
+		}
+	}
+
+	u, q := apiURL(SearchURL)
+	q.Set("systemeid", systemID)
+	q.Set("recherche", name)
+	u.RawQuery = q.Encode()
+
+	res, err := limiter.get(ctx, u.String())
+	if err != nil {
+		return result, err
+	}
+
+	if err := json.Unmarshal(res, &result); err != nil {
+		return result, fmt.Errorf("failed to unmarshal JSON: %w response: %s", err, string(res))
+	}
+	limiter.seedUser(result.Response.Ssuser)
+
+	storeResponse(key, res)
+
+	return result, nil
+}
+
+// rankCandidates scores the search results, best first.
+func rankCandidates(results []Jeu, name, systemID string, regions []string) []Candidate {
+	candidates := make([]Candidate, 0, len(results))
+	for _, jeu := range results {
+		// An empty search returns a single game without an id
+		if jeu.ID == "" {
+			continue
+		}
+		confidence, nameScore := scoreCandidate(jeu, name, systemID, regions)
+		if nameScore < minNameSimilarity {
+			continue
+		}
+		candidates = append(candidates, jeu.toCandidate(confidence))
+	}
+
+	slices.SortStableFunc(candidates, func(a, b Candidate) int {
+		return cmp.Compare(b.Confidence, a.Confidence)
+	})
+
+	return candidates
+}
+
+// scoreCandidate rates a search result between 0 and 1, mostly on how close
+// one of its names is to the rom name, which is returned as well.
+func scoreCandidate(candidate Jeu, name, systemID string, regions []string) (float64, float64) {
+	var nameScore, systemScore, regionScore float64
+
+	for _, nom := range candidate.Noms {
+		nameScore = max(nameScore, similarity(name, nom.Text))
+		if slices.Contains(regions, nom.Region) {
+			regionScore = 1
+		}
+	}
+	if candidate.Systeme.ID == systemID {
+		systemScore = 1
+	}
+
+	return nameWeight*nameScore + systemWeight*systemScore + regionWeight*regionScore, nameScore
+}
+
+// romRegions returns the ScreenScraper regions of the No-Intro style tags in
+// the rom file name, falling back to the configured media regions.
+func romRegions(romPath string) []string {
+	var regions []string
+	for _, match := range romTagsRegex.FindAllStringSubmatch(filepath.Base(romPath), -1) {
+		for _, tag := range strings.Split(match[1], ",") {
+			if region, ok := regionTags[strings.ToLower(strings.TrimSpace(tag))]; ok {
+				regions = append(regions, region)
+			}
+		}
+	}
+	if len(regions) == 0 {
+		return config.PreferredRegions()
+	}
+
+	return regions
+}
+
+// similarity compares two names ignoring case and punctuation, 1 meaning equal.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(normalizeName(a)), []rune(normalizeName(b))
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func normalizeName(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := range a {
+		current[0] = i + 1
+		for j := range b {
+			cost := 1
+			if a[i] == b[j] {
+				cost = 0
+			}
+			current[j+1] = min(previous[j+1]+1, current[j]+1, previous[j]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,189 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected float64
+	}{
+		{"Super Mario World", "super mario world", 1},
+		{"Sonic The Hedgehog 2", "Sonic the Hedgehog 2", 1},
+		{"Street Fighter II", "Street Fighter II': Champion Edition", 0.5},
+		{"", "", 0},
+	}
+
+	for _, tt := range tests {
+		if got := similarity(tt.a, tt.b); got < tt.expected-0.05 || got > tt.expected+0.05 {
+			t.Errorf("similarity(%q, %q) = %.2f, expected about %.2f", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestRomRegions(t *testing.T) {
+	config.Media = config.MediaList{{Regions: []string{"br"}}}
+
+	tests := []struct {
+		rom      string
+		expected []string
+	}{
+		{"/roms/(Hacks)/Game (USA, Europe) (Rev 1).zip", []string{"us", "eu"}},
+		{"Game (Japan).sfc", []string{"jp"}},
+		{"Game [T+Eng].sfc", []string{"br"}},
+	}
+
+	for _, tt := range tests {
+		if got := romRegions(tt.rom); !slices.Equal(got, tt.expected) {
+			t.Errorf("romRegions(%q) = %v, expected %v", tt.rom, got, tt.expected)
+		}
+	}
+}
+
+func TestRankCandidates(t *testing.T) {
+	var results []Jeu
+	err := json.Unmarshal([]byte(`[
+		{"id":"1","systeme":{"id":"4"},"noms":[{"region":"us","text":"Super Mario Land"}]},
+		{"id":"2","systeme":{"id":"4"},"noms":[{"region":"wor","text":"Super Mario World"}],"editeur":{"text":"Nintendo"},"dates":[{"region":"jp","text":"1990-11-21"}]},
+		{"id":"3","systeme":{"id":"5"},"noms":[{"region":"wor","text":"Super Mario World"}]},
+		{"id":"4","systeme":{"id":"4"},"noms":[{"region":"us","text":"Tetris"}]},
+		{}
+	]`), &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	candidates := rankCandidates(results, "Super Mario World", "4", []string{"us"})
+	ids := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		ids = append(ids, candidate.ID)
+	}
+	if !slices.Equal(ids, []string{"2", "1", "3"}) {
+		t.Fatalf("expected candidates [2 1 3], got %v", ids)
+	}
+
+	best := candidates[0]
+	if math.Abs(best.Confidence-(nameWeight+systemWeight)) > 1e-9 {
+		t.Errorf("expected confidence %.2f, got %.2f", nameWeight+systemWeight, best.Confidence)
+	}
+	if best.Year != "1990" || best.Publisher != "Nintendo" || best.Region != "wor" || best.Name != "Super Mario World" {
+		t.Errorf("unexpected candidate details: %+v", best)
+	}
+}
+
+func TestIdentifyGameSearchFallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		minConfidence float64
+		results       string
+		expectedErr   error
+		candidates    int
+	}{
+		{
+			name:          "match above threshold",
+			minConfidence: 0.8,
+			results:       `[{"id":"42","systeme":{"id":"4"},"noms":[{"region":"wor","text":"Super Mario World"}]}]`,
+		},
+		{
+			name:          "match below threshold",
+			minConfidence: 0.95,
+			results:       `[{"id":"42","systeme":{"id":"4"},"noms":[{"region":"wor","text":"Super Mario World"}]}]`,
+			expectedErr:   GameNotFoundErr,
+			candidates:    1,
+		},
+		{
+			name:          "several close matches",
+			minConfidence: 0.8,
+			results:       `[{"id":"42","systeme":{"id":"4"},"noms":[{"region":"wor","text":"Super Mario World"}]},{"id":"43","systeme":{"id":"4"},"noms":[{"region":"wor","text":"Super Mario World!"}]}]`,
+			expectedErr:   GameNotFoundErr,
+			candidates:    2,
+		},
+		{
+			name:          "no results",
+			minConfidence: 0.8,
+			results:       `[{}]`,
+			expectedErr:   GameNotFoundErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/find-game":
+					w.WriteHeader(http.StatusNotFound)
+				case "/search":
+					if r.URL.Query().Get("recherche") != "Super Mario World" {
+						t.Errorf("unexpected search %q", r.URL.Query().Get("recherche"))
+					}
+					_, _ = w.Write([]byte(`{"response":{"jeux":` + tt.results + `}}`))
+				}
+			}))
+			defer server.Close()
+
+			BaseURL = server.URL + "/find-game"
+			SearchURL = server.URL + "/search"
+			config.Cache.Disabled = true
+			config.Cache.Dir = t.TempDir()
+			matchChoices = &choiceStore{}
+			config.Search.MinConfidence = tt.minConfidence
+			defer func() {
+				config.Cache.Disabled = false
+				config.Search.MinConfidence = 0.8
+			}()
+
+			game, err := ScreenScraper{}.IdentifyGame(context.Background(), "4", t.TempDir()+"/Super Mario World (Hack).sfc")
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if tt.expectedErr == nil && (game.ID != "42" || game.MatchedBy != MatchedBySearch) {
+				t.Errorf("expected game 42 matched by search, got %+v", game)
+			}
+
+			var ambiguous *AmbiguousMatchError
+			if errors.As(err, &ambiguous) != (tt.candidates > 0) {
+				t.Fatalf("expected ambiguous match: %v, got %v", tt.candidates > 0, err)
+			}
+			if ambiguous != nil && len(ambiguous.Candidates) != tt.candidates {
+				t.Errorf("expected %d candidates, got %d", tt.candidates, len(ambiguous.Candidates))
+			}
+		})
+	}
+}
+
+func TestIdentifyGameUsesSavedChoice(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("gameid") != "42" {
+			t.Errorf("expected a lookup of game 42, got %s", r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(`{"response":{"jeu":{"id":"42","noms":[{"region":"wor","text":"Super Mario World"}]}}}`))
+	}))
+	defer server.Close()
+
+	BaseURL = server.URL
+	config.Cache.Dir = t.TempDir()
+	rom := "/roms/SFC/Super Mario World (Hack).sfc"
+
+	matchChoices = &choiceStore{}
+	SaveChoice("4", rom, "42")
+
+	// A new store reads the choice back from disk
+	matchChoices = &choiceStore{}
+	game, err := ScreenScraper{}.IdentifyGame(context.Background(), "4", rom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.ID != "42" || game.MatchedBy != MatchedByChoice {
+		t.Errorf("expected game 42 matched by choice, got %+v", game)
+	}
+}
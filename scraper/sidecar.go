@@ -0,0 +1,107 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/anibaldeboni/screech/atomicfile"
+)
+
+// privateParams are the credentials ScreenScraper puts in the media URLs it
+// returns, they never go to the sidecar files.
+var privateParams = []string{"devid", "devpassword", "ssid", "sspassword"}
+
+// Sidecar is the metadata written as JSON next to a scraped image, for
+// scripts and tools that shouldn't have to query ScreenScraper again.
+type Sidecar struct {
+	GameID      string            `json:"gameId"`
+	RomID       string            `json:"romId,omitempty"`
+	Name        string            `json:"name"`
+	Names       map[string]string `json:"names,omitempty"`
+	Synopsis    string            `json:"synopsis,omitempty"`
+	ReleaseDate string            `json:"releaseDate,omitempty"`
+	Dates       map[string]string `json:"dates,omitempty"`
+	Developer   string            `json:"developer,omitempty"`
+	Publisher   string            `json:"publisher,omitempty"`
+	Genres      []string          `json:"genres,omitempty"`
+	Players     string            `json:"players,omitempty"`
+	Rating      float64           `json:"rating,omitempty"`
+	MatchedBy   string            `json:"matchedBy,omitempty"`
+	Media       SidecarMedia      `json:"media"`
+}
+
+// SidecarMedia is the media the image was made from.
+type SidecarMedia struct {
+	Type   string `json:"type"`
+	Region string `json:"region,omitempty"`
+	URL    string `json:"url,omitempty"`
+	CRC    string `json:"crc,omitempty"`
+	MD5    string `json:"md5,omitempty"`
+	SHA1   string `json:"sha1,omitempty"`
+}
+
+func NewSidecar(game Game, media Media) Sidecar {
+	meta := game.Metadata
+	return Sidecar{
+		GameID:      game.ID,
+		RomID:       game.RomID,
+		Name:        game.Name,
+		Names:       meta.Names,
+		Synopsis:    meta.Description,
+		ReleaseDate: meta.ReleaseDate,
+		Dates:       meta.Dates,
+		Developer:   meta.Developer,
+		Publisher:   meta.Publisher,
+		Genres:      meta.Genres,
+		Players:     meta.Players,
+		Rating:      meta.Rating,
+		MatchedBy:   game.MatchedBy,
+		Media: SidecarMedia{
+			Type:   media.Type,
+			Region: media.Region,
+			URL:    publicURL(media.URL),
+			CRC:    media.Crc,
+			MD5:    media.Md5,
+			SHA1:   media.Sha1,
+		},
+	}
+}
+
+// SidecarPath is the path of the sidecar of an image: the image path with a
+// .json extension.
+func SidecarPath(imagePath string) string {
+	return strings.TrimSuffix(imagePath, filepath.Ext(imagePath)) + ".json"
+}
+
+// WriteSidecar saves the sidecar of the game next to the image made from
+// media.
+func WriteSidecar(imagePath string, game Game, media Media) error {
+	data, err := json.MarshalIndent(NewSidecar(game, media), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the sidecar: %w", err)
+	}
+
+	path := SidecarPath(imagePath)
+	if err := atomicfile.WriteFile(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// publicURL drops the credentials from a media URL.
+func publicURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+	q := u.Query()
+	for _, param := range privateParams {
+		q.Del(param)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,75 @@
+package scraper
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteSidecar(t *testing.T) {
+	dir := t.TempDir()
+	image := filepath.Join(dir, "Imgs", "Super Mario World (USA).png")
+
+	game := Game{
+		ID:        "1",
+		RomID:     "42",
+		Name:      "Super Mario World",
+		MatchedBy: "sha1",
+		Metadata: Metadata{
+			Names:       map[string]string{"us": "Super Mario World", "jp": "Super Mario World: Super Mario Bros. 4"},
+			Description: "Mario is back",
+			ReleaseDate: "1991-08-23",
+			Dates:       map[string]string{"us": "1991-08-23"},
+			Genres:      []string{"Platform"},
+			Players:     "1-2",
+			Rating:      0.9,
+		},
+	}
+	media := Media{
+		Type:   "box-3D",
+		Region: "us",
+		URL:    "https://neoclone.screenscraper.fr/api2/mediaJeu.php?devid=dev&devpassword=secret&ssid=user&sspassword=pass&systemeid=4&jeuid=1&media=box-3D(us)",
+		Crc:    "c0ffee",
+	}
+	if err := WriteSidecar(image, game, media); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "Imgs", "Super Mario World (USA).json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret", "devid", "ssid", "pass"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("expected no %q in the sidecar:\n%s", secret, data)
+		}
+	}
+
+	var sidecar Sidecar
+	if err := json.Unmarshal(data, &sidecar); err != nil {
+		t.Fatal(err)
+	}
+	expected := NewSidecar(game, media)
+	expected.Media.URL = "https://neoclone.screenscraper.fr/api2/mediaJeu.php?jeuid=1&media=box-3D%28us%29&systemeid=4"
+	if !reflect.DeepEqual(sidecar, expected) {
+		t.Errorf("expected %+v, got %+v", expected, sidecar)
+	}
+}
+
+func TestSidecarPath(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{"Imgs/game.png", "Imgs/game.json"},
+		{"Imgs/Game (v1.1).jpg", "Imgs/Game (v1.1).json"},
+	}
+	for _, tt := range tests {
+		if path := SidecarPath(tt.image); path != tt.expected {
+			t.Errorf("expected %s, got %s", tt.expected, path)
+		}
+	}
+}
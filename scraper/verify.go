@@ -0,0 +1,134 @@
+package scraper
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+// signature is the start of a file format, offset bytes into the file.
+type signature struct {
+	offset int
+	magic  []byte
+}
+
+// mediaSignatures are the file formats accepted for each media format. Images
+// may come in any of the formats the image processing reads.
+var mediaSignatures = map[MediaFormat][]signature{
+	ImageFormat: {
+		{0, []byte("\x89PNG\r\n\x1a\n")},
+		{0, []byte("\xff\xd8\xff")},
+		{0, []byte("GIF8")},
+		{8, []byte("WEBP")},
+	},
+	VideoFormat: {
+		{4, []byte("ftyp")},
+		{0, []byte("\x1a\x45\xdf\xa3")},
+		{8, []byte("AVI ")},
+		{0, []byte("FLV")},
+	},
+	DocumentFormat: {
+		{0, []byte("%PDF-")},
+	},
+}
+
+// headSize is how much of a file is kept to check its signature
+const headSize = 16
+
+// mediaVerifier checks a download is a file of the media format, not an error
+// page, and, unless the server resized it, that it matches the size and
+// checksums the provider advertised. It is written the file as it's received.
+// A resizable image is taken as resized when its size isn't the advertised
+// one, or there is none, since the server doesn't tell.
+type mediaVerifier struct {
+	media     Media
+	resizable bool
+	head      []byte
+	size      int
+	checksums []checksum
+}
+
+type checksum struct {
+	name     string
+	expected string
+	hash     hash.Hash
+}
+
+func newMediaVerifier(media Media, resizable bool) *mediaVerifier {
+	v := &mediaVerifier{media: media, resizable: resizable}
+	for _, c := range []checksum{
+		{"sha1", media.Sha1, sha1.New()},
+		{"md5", media.Md5, md5.New()},
+		{"crc", media.Crc, crc32.NewIEEE()},
+	} {
+		if c.expected != "" {
+			v.checksums = append(v.checksums, c)
+		}
+	}
+	return v
+}
+
+func (v *mediaVerifier) Write(p []byte) (int, error) {
+	if missing := headSize - len(v.head); missing > 0 {
+		v.head = append(v.head, p[:min(missing, len(p))]...)
+	}
+	v.size += len(p)
+	for _, c := range v.checksums {
+		c.hash.Write(p)
+	}
+	return len(p), nil
+}
+
+func (v *mediaVerifier) verify() error {
+	if v.size == 0 {
+		return fmt.Errorf("%w: empty file", CorruptMediaErr)
+	}
+	format := MediaType(v.media.Type).Format()
+	if !hasSignature(format, v.head) {
+		return fmt.Errorf("%w: not a valid %s file", CorruptMediaErr, format)
+	}
+
+	size, err := strconv.Atoi(v.media.Size)
+	sized := err == nil && size > 0
+	if v.resizable && (!sized || size != v.size) {
+		return nil
+	}
+	if sized && size != v.size {
+		return fmt.Errorf("%w: expected %d bytes, got %d", CorruptMediaErr, size, v.size)
+	}
+	for _, c := range v.checksums {
+		if sum := hex.EncodeToString(c.hash.Sum(nil)); !strings.EqualFold(sum, c.expected) {
+			return fmt.Errorf("%w: %s mismatch", CorruptMediaErr, c.name)
+		}
+	}
+
+	return nil
+}
+
+// verifyMedia checks a whole download, see mediaVerifier.
+func verifyMedia(media Media, data []byte, resizable bool) error {
+	v := newMediaVerifier(media, resizable)
+	v.Write(data)
+	return v.verify()
+}
+
+// hasSignature reports whether data starts like a file of the format. Formats
+// without known signatures are accepted.
+func hasSignature(format MediaFormat, data []byte) bool {
+	signatures, ok := mediaSignatures[format]
+	if !ok {
+		return true
+	}
+	for _, s := range signatures {
+		if len(data) >= s.offset+len(s.magic) && bytes.Equal(data[s.offset:s.offset+len(s.magic)], s.magic) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+func TestVerifyMedia(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nimage data")
+	// Checksums of png
+	const (
+		pngCRC  = "6a86f46f"
+		pngMD5  = "bbe58837833ccc83ebd3d42098013c43"
+		pngSHA1 = "38ae1ec81e42ec92b5d3576b418c7f847a08d983"
+	)
+
+	tests := []struct {
+		name      string
+		media     Media
+		data      []byte
+		resizable bool
+		valid     bool
+	}{
+		{"image", Media{Type: "box-2D"}, png, false, true},
+		{"jpeg image", Media{Type: "box-2D"}, []byte("\xff\xd8\xff\xe0jfif"), false, true},
+		{"webp image", Media{Type: "ss"}, []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), false, true},
+		{"html error page", Media{Type: "box-2D"}, []byte("<html><body>Error</body></html>"), false, false},
+		{"empty file", Media{Type: "box-2D"}, nil, true, false},
+		{"video", Media{Type: "video"}, []byte("\x00\x00\x00\x18ftypmp42"), false, true},
+		{"not a video", Media{Type: "video"}, png, false, false},
+		{"manual", Media{Type: "manuel"}, []byte("%PDF-1.4"), false, true},
+		{"matching checksums", Media{Type: "box-2D", Crc: pngCRC, Md5: pngMD5, Sha1: pngSHA1, Size: "18"}, png, false, true},
+		{"matching crc upper case", Media{Type: "box-2D", Crc: "6A86F46F"}, png, false, true},
+		{"crc mismatch", Media{Type: "box-2D", Crc: "00000000"}, png, false, false},
+		{"md5 mismatch", Media{Type: "box-2D", Md5: "00000000000000000000000000000000"}, png, false, false},
+		{"sha1 mismatch", Media{Type: "box-2D", Sha1: "0000000000000000000000000000000000000000"}, png, false, false},
+		{"truncated", Media{Type: "box-2D", Size: "1000"}, png, false, false},
+		{"resized image", Media{Type: "box-2D", Crc: "00000000", Size: "1000"}, png, true, true},
+		{"resizable image without size", Media{Type: "box-2D", Crc: "00000000"}, png, true, true},
+		{"image not resized", Media{Type: "box-2D", Crc: pngCRC, Size: "18"}, png, true, true},
+		{"image not resized crc mismatch", Media{Type: "box-2D", Crc: "00000000", Size: "18"}, png, true, false},
+		{"resized html", Media{Type: "box-2D"}, []byte("<html>"), true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyMedia(tt.media, tt.data, tt.resizable)
+			if tt.valid && err != nil {
+				t.Errorf("did not expect error but got: %v", err)
+			}
+			if !tt.valid {
+				if !errors.Is(err, CorruptMediaErr) {
+					t.Errorf("expected a corrupt media error, got %v", err)
+				}
+				if !IsRetryable(err) {
+					t.Errorf("expected %v to be retryable", err)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchMediaRetriesCorruptDownload(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = time.Second }()
+	config.MaxAttempts = 3
+
+	video := []byte("\x00\x00\x00\x18ftypmp42video data")
+	responses := [][]byte{
+		[]byte("<html><body>Server error</body></html>"),
+		video[:12],
+		video,
+	}
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(responses[min(requests, len(responses)-1)])
+		requests++
+	}))
+	defer server.Close()
+
+	media := Media{Type: "video", URL: server.URL, Size: "22"}
+	dest := filepath.Join(t.TempDir(), "game.mp4")
+	if err := (ScreenScraper{}).FetchMedia(context.Background(), media, dest); err != nil {
+		t.Fatalf("did not expect error but got: %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != string(video) {
+		t.Errorf("expected the complete video to be saved, got %q", data)
+	}
+
+	// Corrupt downloads are never saved
+	requests = 0
+	responses = responses[:1]
+	dest = filepath.Join(t.TempDir(), "game.mp4")
+	if err := (ScreenScraper{}).FetchMedia(context.Background(), media, dest); !errors.Is(err, CorruptMediaErr) {
+		t.Errorf("expected a corrupt media error, got %v", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("expected no file to be saved")
+	}
+}
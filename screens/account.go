@@ -0,0 +1,119 @@
+package screens
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/anibaldeboni/screech/components"
+	"github.com/anibaldeboni/screech/config"
+	"github.com/anibaldeboni/screech/input"
+	"github.com/anibaldeboni/screech/scraper"
+	"github.com/anibaldeboni/screech/uilib"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+var fetchUserInfo = scraper.FetchUserInfo
+
+type AccountScreen struct {
+	renderer    *sdl.Renderer
+	textView    *components.TextView
+	cancel      context.CancelFunc
+	initialized bool
+}
+
+func NewAccountScreen(renderer *sdl.Renderer) (*AccountScreen, error) {
+	return &AccountScreen{
+		renderer: renderer,
+	}, nil
+}
+
+func (a *AccountScreen) InitAccount() {
+	if a.initialized {
+		return
+	}
+	a.textView = components.NewTextView(
+		a.renderer,
+		components.TextViewSize{Width: 100, Height: 18},
+		sdl.Point{X: 45, Y: 95},
+	)
+	a.initialized = true
+
+	var ctx context.Context
+	ctx, a.cancel = context.WithCancel(context.Background())
+
+	a.textView.AddText("Fetching account details...")
+	go func() {
+		user, err := fetchUserInfo(ctx)
+		if err != nil {
+			for _, line := range explainFatalError(err, time.Now()) {
+				a.textView.AddText(line)
+			}
+			return
+		}
+		for _, line := range describeAccount(user, time.Now()) {
+			a.textView.AddText(line)
+		}
+	}()
+}
+
+func (a *AccountScreen) HandleInput(event input.UserInputEvent) {
+	switch event.KeyCode {
+	case "DOWN":
+		a.textView.ScrollDown(1)
+	case "UP":
+		a.textView.ScrollUp(1)
+	case "B":
+		a.cancel()
+		config.CurrentScreen = "home_screen"
+		a.initialized = false
+	}
+}
+
+func (a *AccountScreen) Draw() {
+	a.InitAccount()
+
+	_ = a.renderer.SetDrawColor(0, 0, 0, 255)
+	_ = a.renderer.Clear()
+
+	uilib.RenderTexture(a.renderer, config.UiBackground, "Q2", "Q4")
+	uilib.RenderTexture(a.renderer, config.UiOverlay, "Q2", "Q4")
+	uilib.DrawText(
+		a.renderer,
+		"ScreenScraper account",
+		sdl.Point{X: 25, Y: 25},
+		config.Colors.PRIMARY, config.HeaderFont,
+	)
+
+	a.textView.Draw(config.Colors.WHITE)
+
+	uilib.RenderTexture(a.renderer, config.UiControls, "Q3", "Q4")
+
+	a.renderer.Present()
+}
+
+func describeAccount(user scraper.UserInfo, now time.Time) []string {
+	return []string{
+		fmt.Sprintf("User: %s (level %s)", user.ID, user.Niveau),
+		fmt.Sprintf("Requests today: %s / %s (%s left)", user.Requeststoday, user.Maxrequestsperday, remaining(user.Requeststoday, user.Maxrequestsperday)),
+		fmt.Sprintf("Unrecognized roms today: %s / %s", user.Requestskotoday, user.Maxrequestskoperday),
+		fmt.Sprintf("Requests per minute: %s", user.Maxrequestspermin),
+		fmt.Sprintf("Threads: %s", user.Maxthreads),
+		fmt.Sprintf("Download speed: %s KB/s", user.Maxdownloadspeed),
+		fmt.Sprintf("The quota resets at %s.", scraper.QuotaResetTime(now).Local().Format("Jan 2 15:04")),
+	}
+}
+
+func remaining(used, total string) string {
+	u, err := strconv.Atoi(used)
+	if err != nil {
+		return "?"
+	}
+	t, err := strconv.Atoi(total)
+	if err != nil {
+		return "?"
+	}
+	return strconv.Itoa(max(t-u, 0))
+}
@@ -0,0 +1,107 @@
+package screens
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/anibaldeboni/screech/config"
+)
+
+// RunHeadless scrapes the given system dirs without the graphical interface and
+// returns the process exit status. When no dir is given every system found in
+// the roms folder is scraped.
+func RunHeadless(systemDirs []string) int {
+	systems, err := headlessTargets(systemDirs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	interrupted, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(interrupted)
+	defer cancel()
+
+	if err := checkAccount(ctx, systems); err != nil {
+		for _, line := range explainFatalError(err, time.Now()) {
+			fmt.Fprintln(os.Stderr, line)
+		}
+		return 1
+	}
+
+	count, scanErrors := scrapeHeadless(ctx, cancel, systems, os.Stdout)
+	if interrupted.Err() != nil || count.failed.Load() > 0 || scanErrors > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// scrapeHeadless prints the scraping events to out and returns the counts of
+// the roms and of the system dirs that could not be read.
+func scrapeHeadless(ctx context.Context, cancel context.CancelFunc, systems []romDirSettings, out io.Writer) (*counter, uint32) {
+	events := make(chan string)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for msg := range events {
+			fmt.Fprintln(out, msg)
+		}
+	}()
+
+	roms, scanErrors := findRoms(ctx, events, systems, config.MaxScanDepth)
+	count := buildWorkerPool(ctx, cancel, max(config.Threads, 1), roms, events)
+	<-done
+
+	return count, scanErrors.Load()
+}
+
+func headlessTargets(systemDirs []string) ([]romDirSettings, error) {
+	var romDirs []RomDir
+
+	if len(systemDirs) == 0 {
+		dirs, err := listRomsDirs()
+		if err != nil {
+			return nil, err
+		}
+		romDirs = dirs
+	}
+
+	for _, dir := range systemDirs {
+		path := dir
+		if exists, _ := dirExists(path); !exists {
+			path = filepath.Join(config.RomsBaseDir, dir)
+		}
+
+		if exists, err := dirExists(path); err != nil {
+			return nil, fmt.Errorf("error checking directory %s: %w", dir, err)
+		} else if !exists {
+			return nil, fmt.Errorf("directory %s does not exist", dir)
+		}
+
+		romDirs = append(romDirs, RomDir{
+			Name: filepath.Base(filepath.Clean(path)),
+			Path: path,
+		})
+	}
+
+	if len(romDirs) == 0 {
+		return nil, fmt.Errorf("no system dirs found in %s", config.RomsBaseDir)
+	}
+
+	items := romDirsToList(romDirs)
+	systems := make([]romDirSettings, 0, len(items))
+	for _, item := range items {
+		systems = append(systems, item.Value)
+	}
+
+	return systems, nil
+}
@@ -0,0 +1,134 @@
+package screens
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/screech/config"
+	"github.com/anibaldeboni/screech/scraper"
+)
+
+func TestHeadlessTargets(t *testing.T) {
+	baseDir := t.TempDir()
+	_ = os.Mkdir(filepath.Join(baseDir, "SFC"), 0755)
+	_ = os.WriteFile(filepath.Join(baseDir, "SFC", "game1.sfc"), []byte{}, 0644)
+	_ = os.Mkdir(filepath.Join(baseDir, "GB"), 0755)
+	_ = os.WriteFile(filepath.Join(baseDir, "GB", "game1.gb"), []byte{}, 0644)
+
+	config.RomsBaseDir = baseDir
+	config.Systems = map[string]config.SystemSettings{
+		"SFC": {ID: "4", Name: "Super Nintendo", OutputDir: "SFC"},
+	}
+
+	tests := []struct {
+		name      string
+		dirs      []string
+		expected  []string
+		expectErr bool
+	}{
+		{
+			name:     "Dir name relative to roms folder",
+			dirs:     []string{"SFC"},
+			expected: []string{"4"},
+		},
+		{
+			name:     "Absolute dir path",
+			dirs:     []string{filepath.Join(baseDir, "SFC")},
+			expected: []string{"4"},
+		},
+		{
+			name:     "All systems when no dir is given",
+			dirs:     nil,
+			expected: []string{"", "4"},
+		},
+		{
+			name:      "Directory does not exist",
+			dirs:      []string{"N64"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			systems, err := headlessTargets(tt.dirs)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error but got: %v", err)
+			}
+
+			if len(systems) != len(tt.expected) {
+				t.Fatalf("expected %d systems, got %d", len(tt.expected), len(systems))
+			}
+
+			for i, system := range systems {
+				if system.SystemID != tt.expected[i] {
+					t.Errorf("expected system id %q, got %q", tt.expected[i], system.SystemID)
+				}
+			}
+		})
+	}
+}
+
+func TestScrapeHeadless(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "game1.rom"), []byte{}, 0644)
+	_ = os.WriteFile(filepath.Join(dir, "game2.rom"), []byte{}, 0644)
+
+	originalGetProvider := getProvider
+	originalDownloadMedia := downloadMedia
+	originalHasScrapedImage := hasScrapedImage
+	defer func() {
+		getProvider = originalGetProvider
+		downloadMedia = originalDownloadMedia
+		hasScrapedImage = originalHasScrapedImage
+	}()
+
+	getProvider = stubProvider(func(ctx context.Context, systemID string, rom string) (scraper.Game, error) {
+		if filepath.Base(rom) == "game2.rom" {
+			return scraper.Game{}, errors.New("scraping error")
+		}
+		return scraper.Game{}, nil
+	})
+	downloadMedia = func(ctx context.Context, provider scraper.Provider, game scraper.Game, media config.ScrapeMedia, dest string) (scraper.Media, error) {
+		return scraper.Media{}, nil
+	}
+	hasScrapedImage = func(string) bool { return false }
+
+	config.ExcludeExtensions = []string{".txt"}
+	config.MaxScanDepth = 2
+	config.Threads = 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var out bytes.Buffer
+	count, scanErrors := scrapeHeadless(ctx, cancel, []romDirSettings{{DirName: "roms", Path: dir, SystemID: "1"}, {DirName: "missing", Path: filepath.Join(dir, "missing")}}, &out)
+
+	if count.success.Load() != 1 {
+		t.Errorf("expected 1 success, got %d", count.success.Load())
+	}
+	if count.failed.Load() != 1 {
+		t.Errorf("expected 1 failed, got %d", count.failed.Load())
+	}
+	// A missing dir fails the run without stopping the scan of the others
+	if scanErrors != 1 {
+		t.Errorf("expected 1 scan error, got %d", scanErrors)
+	}
+
+	for _, expected := range []string{"Scraped game1", "Error scraping game2: scraping error", "Scraping finished.", "Failed: 1"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected output to contain %q, got %s", expected, out.String())
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/anibaldeboni/screech/components"
 	"github.com/anibaldeboni/screech/config"
 	"github.com/anibaldeboni/screech/input"
+	"github.com/anibaldeboni/screech/scraper"
 	"github.com/anibaldeboni/screech/uilib"
 
 	"slices"
@@ -29,6 +30,10 @@ type romDirSettings struct {
 	OutputDir  string
 	SystemName string
 	SystemID   string
+	Provider   string
+	Overrides  string
+	Exports    []string
+	Playlist   string
 }
 
 func NewHomeScreen(renderer *sdl.Renderer) (*HomeScreen, error) {
@@ -80,6 +85,9 @@ func (h *HomeScreen) HandleInput(event input.UserInputEvent) {
 		if h.isNotInErrorMode() {
 			h.goToScraping(h.systemsList.GetValues())
 		}
+	case "Y":
+		config.CurrentScreen = "account_screen"
+		h.initialized = false
 	}
 }
 
@@ -146,12 +154,26 @@ func romDirsToList(romDirs []RomDir) []components.Item[romDirSettings] {
 				OutputDir:  system.OutputDir,
 				SystemName: label,
 				SystemID:   system.ID,
+				Provider:   system.Provider,
+				Overrides:  system.Overrides,
+				Exports:    system.ExportFormats(),
+				Playlist:   playlistName(system),
 			},
 		})
 	}
 	return items
 }
 
+// playlistName returns the libretro database name of a system, which the
+// libretro provider uses as system id. Other systems have none unless set,
+// RetroArch only matches its database and thumbnails with the libretro names.
+func playlistName(system config.SystemSettings) string {
+	if system.Playlist == "" && system.Provider == scraper.LibretroProvider {
+		return system.ID
+	}
+	return system.Playlist
+}
+
 func sortItemsAlphabetically(items []components.Item[romDirSettings]) []components.Item[romDirSettings] {
 	slices.SortFunc(items, func(a, b components.Item[romDirSettings]) int {
 		if a.Label < b.Label {
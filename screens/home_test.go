@@ -0,0 +1,29 @@
+package screens
+
+import (
+	"testing"
+
+	"github.com/anibaldeboni/screech/config"
+	"github.com/anibaldeboni/screech/scraper"
+)
+
+func TestPlaylistName(t *testing.T) {
+	tests := []struct {
+		name     string
+		system   config.SystemSettings
+		expected string
+	}{
+		{"playlist set", config.SystemSettings{ID: "4", Playlist: "Nintendo - Super Nintendo Entertainment System"}, "Nintendo - Super Nintendo Entertainment System"},
+		{"libretro id", config.SystemSettings{ID: "Nintendo - Game Boy", Provider: scraper.LibretroProvider}, "Nintendo - Game Boy"},
+		// A display name would not match the RetroArch database
+		{"screenscraper without playlist", config.SystemSettings{ID: "4", Name: "SNES"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := playlistName(tt.system); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package screens
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/anibaldeboni/screech/components"
+	"github.com/anibaldeboni/screech/config"
+	"github.com/anibaldeboni/screech/input"
+	"github.com/anibaldeboni/screech/scraper"
+	"github.com/anibaldeboni/screech/uilib"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const previewsDir = "previews"
+
+var fetchPreviewMedia = scraper.FetchPreview
+
+// matchRequest is sent by a worker that needs the user to pick the game of a
+// rom. The index of the chosen candidate, or -1 to skip, is sent back on choice.
+type matchRequest struct {
+	rom        Rom
+	candidates []scraper.Candidate
+	choice     chan int
+}
+
+var (
+	matchRequests = make(chan matchRequest)
+	pendingMatch  matchRequest
+)
+
+type PickerScreen struct {
+	renderer       *sdl.Renderer
+	candidatesList *components.List[scraper.Candidate]
+	previews       sync.Map
+	initialized    bool
+}
+
+func NewPickerScreen(renderer *sdl.Renderer) (*PickerScreen, error) {
+	return &PickerScreen{
+		renderer: renderer,
+		candidatesList: components.NewList(
+			renderer,
+			16,
+			sdl.Point{X: 45, Y: 125},
+			func(index int, item components.Item[scraper.Candidate]) string {
+				return fmt.Sprintf("%d. %s", index+1, item.Label)
+			},
+		),
+	}, nil
+}
+
+func SetMatchRequest(req matchRequest) {
+	pendingMatch = req
+}
+
+// requestMatch hands the candidates over to the picker screen and waits for the
+// user to choose one.
+func requestMatch(ctx context.Context, rom Rom, candidates []scraper.Candidate) (scraper.Candidate, bool) {
+	req := matchRequest{rom: rom, candidates: candidates, choice: make(chan int, 1)}
+
+	select {
+	case matchRequests <- req:
+	case <-ctx.Done():
+		return scraper.Candidate{}, false
+	}
+
+	select {
+	case index := <-req.choice:
+		if index < 0 || index >= len(candidates) {
+			return scraper.Candidate{}, false
+		}
+		return candidates[index], true
+	case <-ctx.Done():
+		return scraper.Candidate{}, false
+	}
+}
+
+func (p *PickerScreen) InitPicker() {
+	if p.initialized {
+		return
+	}
+	p.candidatesList.SetItems(candidatesToList(pendingMatch.candidates))
+	p.initialized = true
+}
+
+func (p *PickerScreen) HandleInput(event input.UserInputEvent) {
+	switch event.KeyCode {
+	case "DOWN":
+		p.candidatesList.ScrollDown()
+	case "UP":
+		p.candidatesList.ScrollUp()
+	case "A":
+		p.choose(p.candidatesList.GetSelectedIndex())
+	case "B":
+		p.choose(-1)
+	}
+}
+
+func (p *PickerScreen) choose(index int) {
+	pendingMatch.choice <- index
+	config.CurrentScreen = "scraping_screen"
+	p.initialized = false
+}
+
+func (p *PickerScreen) Draw() {
+	p.InitPicker()
+
+	_ = p.renderer.SetDrawColor(0, 0, 0, 255)
+	_ = p.renderer.Clear()
+
+	uilib.RenderTexture(p.renderer, config.UiBackground, "Q2", "Q4")
+	uilib.DrawText(p.renderer, "Choose the game", sdl.Point{X: 25, Y: 25}, config.Colors.PRIMARY, config.HeaderFont)
+	uilib.DrawText(p.renderer, pendingMatch.rom.Name, sdl.Point{X: 45, Y: 85}, config.Colors.SECONDARY, config.LongTextFont)
+	uilib.RenderTexture(p.renderer, config.UiOverlaySelection, "Q2", "Q4")
+
+	p.candidatesList.Draw(config.Colors.WHITE, config.Colors.SECONDARY)
+
+	uilib.RenderTexture(p.renderer, config.UiControls, "Q3", "Q4")
+
+	p.drawPreview(p.candidatesList.SelectedValue())
+
+	p.renderer.Present()
+}
+
+// drawPreview shows the boxart of the candidate, downloading it in the
+// background the first time it is selected.
+func (p *PickerScreen) drawPreview(candidate scraper.Candidate) {
+	if candidate.ID == "" {
+		return
+	}
+
+	base := filepath.Join(config.CacheDir(), previewsDir, candidate.ID)
+	dest := scraper.MediaPath(base, scraper.Media{Type: string(scraper.Box2D)})
+	ready, requested := p.previews.LoadOrStore(candidate.ID, false)
+	if !requested {
+		go p.fetchPreview(pendingMatch.rom.Provider, candidate, dest)
+		return
+	}
+	if ready.(bool) {
+		uilib.RenderImage(p.renderer, dest)
+	}
+}
+
+func (p *PickerScreen) fetchPreview(providerName string, candidate scraper.Candidate, dest string) {
+	if _, err := os.Stat(dest); err == nil {
+		p.previews.Store(candidate.ID, true)
+		return
+	}
+
+	provider, err := getProvider(providerName)
+	if err != nil {
+		return
+	}
+	if err := fetchPreviewMedia(context.Background(), provider, candidate.Game, previewMedia(), dest); err == nil {
+		p.previews.Store(candidate.ID, true)
+	}
+}
+
+func candidatesToList(candidates []scraper.Candidate) []components.Item[scraper.Candidate] {
+	items := make([]components.Item[scraper.Candidate], 0, len(candidates))
+	for _, candidate := range candidates {
+		items = append(items, components.Item[scraper.Candidate]{
+			Label: candidateLabel(candidate),
+			Value: candidate,
+		})
+	}
+	return items
+}
+
+func candidateLabel(candidate scraper.Candidate) string {
+	label := candidate.Name
+	if candidate.Year != "" {
+		label += " (" + candidate.Year + ")"
+	}
+	if candidate.Publisher != "" {
+		label += " - " + candidate.Publisher
+	}
+	if candidate.Region != "" {
+		label += " [" + candidate.Region + "]"
+	}
+	return fmt.Sprintf("%s %.0f%%", label, candidate.Confidence*100)
+}
+
+func previewMedia() config.ScrapeMedia {
+	return config.ScrapeMedia{
+		Type:                config.MediaTypes{string(scraper.Box2D)},
+		Regions:             config.PreferredRegions(),
+		IgnoreMissingRegion: true,
+	}
+}
@@ -0,0 +1,112 @@
+package screens
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/screech/scraper"
+)
+
+func TestIdentifyGameAmbiguousMatch(t *testing.T) {
+	candidates := []scraper.Candidate{
+		{Game: scraper.Game{ID: "1", Name: "Super Mario World"}},
+		{Game: scraper.Game{ID: "2", Name: "Super Mario World 2"}},
+	}
+	provider := fakeProvider{identifyGame: func(ctx context.Context, systemID string, romPath string) (scraper.Game, error) {
+		return scraper.Game{}, &scraper.AmbiguousMatchError{Rom: "Super Mario World", Candidates: candidates}
+	}}
+	rom := Rom{Name: "Super Mario World (Hack).sfc", Path: "/roms/SFC/Super Mario World (Hack).sfc", SystemID: "4"}
+
+	originalPickMatch := pickMatch
+	originalSaveChoice := saveChoice
+	defer func() {
+		pickMatch = originalPickMatch
+		saveChoice = originalSaveChoice
+	}()
+
+	tests := []struct {
+		name        string
+		pick        func(ctx context.Context, rom Rom, candidates []scraper.Candidate) (scraper.Candidate, bool)
+		expectedID  string
+		expectedErr error
+		saved       string
+	}{
+		{
+			name: "user picks a candidate",
+			pick: func(ctx context.Context, rom Rom, candidates []scraper.Candidate) (scraper.Candidate, bool) {
+				return candidates[1], true
+			},
+			expectedID: "2",
+			saved:      "2",
+		},
+		{
+			name: "user skips",
+			pick: func(ctx context.Context, rom Rom, candidates []scraper.Candidate) (scraper.Candidate, bool) {
+				return scraper.Candidate{}, false
+			},
+			expectedErr: matchSkippedErr,
+		},
+		{
+			name:        "no picker",
+			expectedErr: scraper.GameNotFoundErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved string
+			saveChoice = func(systemID, romPath, gameID string) { saved = gameID }
+			pickMatch = tt.pick
+
+			game, err := identifyGame(context.Background(), provider, rom)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if game.ID != tt.expectedID {
+				t.Errorf("expected game %q, got %q", tt.expectedID, game.ID)
+			}
+			if tt.expectedID != "" && game.MatchedBy != scraper.MatchedByChoice {
+				t.Errorf("expected game matched by choice, got %q", game.MatchedBy)
+			}
+			if saved != tt.saved {
+				t.Errorf("expected choice %q to be saved, got %q", tt.saved, saved)
+			}
+		})
+	}
+}
+
+func TestRequestMatch(t *testing.T) {
+	candidates := []scraper.Candidate{{Game: scraper.Game{ID: "1"}}, {Game: scraper.Game{ID: "2"}}}
+
+	go func() {
+		req := <-matchRequests
+		req.choice <- 1
+	}()
+
+	candidate, ok := requestMatch(context.Background(), Rom{Name: "game.rom"}, candidates)
+	if !ok || candidate.ID != "2" {
+		t.Errorf("expected candidate 2, got %+v (%v)", candidate, ok)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, ok := requestMatch(ctx, Rom{Name: "game.rom"}, candidates); ok {
+		t.Error("expected no candidate once the context is done")
+	}
+}
+
+func TestCandidateLabel(t *testing.T) {
+	candidate := scraper.Candidate{
+		Game:       scraper.Game{Name: "Super Mario World"},
+		Year:       "1990",
+		Publisher:  "Nintendo",
+		Region:     "jp",
+		Confidence: 0.87,
+	}
+
+	if label := candidateLabel(candidate); label != "Super Mario World (1990) - Nintendo [jp] 87%" {
+		t.Errorf("unexpected label %q", label)
+	}
+}
@@ -11,9 +11,11 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/anibaldeboni/screech/components"
 	"github.com/anibaldeboni/screech/config"
+	"github.com/anibaldeboni/screech/export"
 	"github.com/anibaldeboni/screech/input"
 	"github.com/anibaldeboni/screech/scraper"
 	"github.com/anibaldeboni/screech/uilib"
@@ -23,13 +25,25 @@ import (
 
 var (
 	scraping        bool
-	findGame        = scraper.FindGame
+	getProvider     = scraper.GetProvider
 	downloadMedia   = scraper.DownloadMedia
+	refreshMedia    = scraper.RefreshMedia
+	needsRefresh    = scraper.NeedsRefresh
+	cachedGame      = scraper.CachedGame
+	validateAccount = scraper.ValidateCredentials
+	saveChoice      = scraper.SaveChoice
+	lookupOverride  = scraper.LookupOverride
+	// pickMatch lets the user choose among the candidates of an ambiguous rom.
+	// Without it, as in headless mode, the rom is reported as not found.
+	pickMatch       func(ctx context.Context, rom Rom, candidates []scraper.Candidate) (scraper.Candidate, bool)
+	matchSkippedErr = errors.New("no game chosen")
 	hasScrapedImage = func(scrapeFile string) bool {
 		_, err := os.Stat(scrapeFile)
 		return !os.IsNotExist(err)
 	}
 	targetSystems []romDirSettings
+	// exports collects the scraped games to export once the run is done
+	exports *export.Batch
 )
 
 type ScrapingScreen struct {
@@ -43,14 +57,35 @@ type ScrapingScreen struct {
 type Rom struct {
 	Name,
 	Path,
+	SystemDir,
+	SystemName,
 	OutputDir,
-	SystemID string
+	SystemID,
+	Provider,
+	Overrides,
+	Playlist string
+	Exports []string
 }
 
 type counter struct {
 	success, failed, skipped *atomic.Uint32
 }
 
+// breaker stops the whole pool the first time an account or app level error
+// shows up, since every following request would fail the same way.
+type breaker struct {
+	once   sync.Once
+	cancel context.CancelFunc
+	err    error
+}
+
+func (b *breaker) trip(err error) {
+	b.once.Do(func() {
+		b.err = err
+		b.cancel()
+	})
+}
+
 func NewScrapingScreen(renderer *sdl.Renderer) (*ScrapingScreen, error) {
 	return &ScrapingScreen{
 		renderer: renderer,
@@ -96,6 +131,14 @@ func (s *ScrapingScreen) HandleInput(event input.UserInputEvent) {
 func (s *ScrapingScreen) Draw() {
 	s.InitScraping()
 
+	select {
+	case req := <-matchRequests:
+		SetMatchRequest(req)
+		config.CurrentScreen = "picker_screen"
+		return
+	default:
+	}
+
 	_ = s.renderer.SetDrawColor(0, 0, 0, 255) // Background color
 	_ = s.renderer.Clear()
 
@@ -134,22 +177,42 @@ func (s *ScrapingScreen) scrape() {
 		scraping = true
 	}
 	s.ctx, s.cancel = context.WithCancel(context.Background())
+	pickMatch = requestMatch
 	events := make(chan string)
-	roms := findRoms(s.ctx, events, targetSystems, config.MaxScanDepth)
-
-	if roms == nil {
-		events <- "Scraping aborted!"
-		s.cancel()
-		return
-	}
-
-	go buildWorkerPool(s.ctx, s.cancel, config.Threads, roms, events)
 
 	go func(ch <-chan string) {
 		for msg := range ch {
 			s.textView.AddText(msg)
 		}
 	}(events)
+
+	go func() {
+		if err := checkAccount(s.ctx, targetSystems); err != nil {
+			events <- "Scraping stopped!"
+			for _, line := range explainFatalError(err, time.Now()) {
+				events <- line
+			}
+			s.cancel()
+			close(events)
+			return
+		}
+
+		roms, _ := findRoms(s.ctx, events, targetSystems, config.MaxScanDepth)
+		buildWorkerPool(s.ctx, s.cancel, config.Threads, roms, events)
+	}()
+}
+
+// checkAccount validates the ScreenScraper login before scraping systems that
+// use it. Anonymous scraping is left alone.
+func checkAccount(ctx context.Context, systems []romDirSettings) error {
+	if config.Username == "" || !slices.ContainsFunc(systems, usesScreenScraper) {
+		return nil
+	}
+	return validateAccount(ctx)
+}
+
+func usesScreenScraper(system romDirSettings) bool {
+	return system.Provider == "" || system.Provider == scraper.DefaultProvider
 }
 
 func calculateDepth(rootDir, targetDir string) (int, error) {
@@ -174,18 +237,23 @@ func dirExists(path string) (bool, error) {
 	return info.IsDir(), nil
 }
 
-func findRoms(ctx context.Context, events chan<- string, romDirs []romDirSettings, maxDepth int) <-chan Rom {
+// findRoms walks the system dirs and sends the roms found. The returned
+// counter holds the dirs that could not be read, known once roms is closed.
+func findRoms(ctx context.Context, events chan<- string, romDirs []romDirSettings, maxDepth int) (<-chan Rom, *atomic.Uint32) {
 	roms := make(chan Rom, 15)
+	scanErrors := new(atomic.Uint32)
 
 	go func() {
 		defer close(roms)
 		for _, romDir := range romDirs {
 			if exists, err := dirExists(romDir.Path); err != nil {
 				events <- fmt.Sprintf("Error checking directory: %v", err)
-				return
+				scanErrors.Add(1)
+				continue
 			} else if !exists {
 				events <- fmt.Sprintf("Directory %s does not exist", romDir.Path)
-				return
+				scanErrors.Add(1)
+				continue
 			}
 			err := filepath.WalkDir(
 				romDir.Path,
@@ -212,10 +280,16 @@ func findRoms(ctx context.Context, events chan<- string, romDirs []romDirSetting
 							events <- "Walking " + strings.TrimPrefix(path, config.RomsBaseDir)
 						} else {
 							roms <- Rom{
-								Name:      filepath.Base(path),
-								Path:      path,
-								OutputDir: romDir.OutputDir,
-								SystemID:  romDir.SystemID,
+								Name:       filepath.Base(path),
+								Path:       path,
+								SystemDir:  romDir.Path,
+								SystemName: romDir.SystemName,
+								OutputDir:  romDir.OutputDir,
+								SystemID:   romDir.SystemID,
+								Provider:   romDir.Provider,
+								Overrides:  romDir.Overrides,
+								Playlist:   romDir.Playlist,
+								Exports:    romDir.Exports,
 							}
 						}
 						return nil
@@ -223,40 +297,77 @@ func findRoms(ctx context.Context, events chan<- string, romDirs []romDirSetting
 				})
 			if err != nil && !errors.Is(err, context.Canceled) {
 				events <- fmt.Sprintf("Error walking the path: %v", err)
+				scanErrors.Add(1)
 			}
 		}
 	}()
 
-	return roms
+	return roms, scanErrors
 }
 
-func buildWorkerPool(ctx context.Context, cancel context.CancelFunc, workers int, roms <-chan Rom, events chan<- string) {
+func buildWorkerPool(ctx context.Context, cancel context.CancelFunc, workers int, roms <-chan Rom, events chan<- string) *counter {
 	var (
 		success, failed, skipped atomic.Uint32
 		wg                       sync.WaitGroup
 	)
+	count := &counter{&success, &failed, &skipped}
+	stop := &breaker{cancel: cancel}
+	exports = export.NewBatch()
 
 	wg.Add(workers)
 	for range workers {
-		go worker(ctx, &wg, roms, events, &counter{&success, &failed, &skipped})
+		go worker(ctx, &wg, roms, events, count, stop)
 	}
 
 	go func() {
 		wg.Wait()
 		defer close(events)
-		var completionMsg string
-		if errors.Is(ctx.Err(), context.Canceled) {
-			completionMsg = "Scraping aborted!"
+		if err := scraper.SaveHashIndex(); err != nil {
+			events <- err.Error()
+		}
+		if err := scraper.SaveMediaIndex(); err != nil {
+			events <- err.Error()
+		}
+		if err := exports.Write(); err != nil {
+			events <- fmt.Sprintf("Error exporting the games: %v", err)
+		}
+		if stop.err != nil {
+			events <- "Scraping stopped!"
+			for _, line := range explainFatalError(stop.err, time.Now()) {
+				events <- line
+			}
+		} else if errors.Is(ctx.Err(), context.Canceled) {
+			events <- "Scraping aborted!"
 		} else {
-			completionMsg = "Scraping finished."
+			events <- "Scraping finished."
 			cancel()
 		}
 
-		events <- completionMsg
 		events <- fmt.Sprintf("Success: %d", success.Load())
 		events <- fmt.Sprintf("Failed: %d", failed.Load())
 		events <- fmt.Sprintf("Skipped: %d", skipped.Load())
 	}()
+
+	return count
+}
+
+func explainFatalError(err error, now time.Time) []string {
+	resetAt := fmt.Sprintf("The quota resets at %s.", scraper.QuotaResetTime(now).Local().Format("Jan 2 15:04"))
+
+	switch {
+	case errors.Is(err, scraper.ScrapeQuotaErr):
+		return []string{"The daily ScreenScraper request quota has been used up.", resetAt}
+	case errors.Is(err, scraper.ToowManyUnknownRomsErr):
+		return []string{"ScreenScraper refused more unrecognized roms for today.", resetAt}
+	case errors.Is(err, scraper.AppHasBeenBlockedErr):
+		return []string{"Screech has been blocked by ScreenScraper, please update it."}
+	case errors.Is(err, scraper.InvalidCredentialsErr):
+		return []string{"ScreenScraper did not accept your username or password, check them in the config file."}
+	case errors.Is(err, scraper.DevLoginErr):
+		return []string{"ScreenScraper refused the login, check your username and password."}
+	default:
+		return []string{err.Error()}
+	}
 }
 
 func worker(
@@ -265,6 +376,7 @@ func worker(
 	roms <-chan Rom,
 	events chan<- string,
 	count *counter,
+	stop *breaker,
 ) {
 	defer wg.Done()
 
@@ -279,35 +391,237 @@ download:
 			}
 
 			romName := strings.TrimSuffix(rom.Name, filepath.Ext(rom.Name))
-			scrapeFile := filepath.Join(config.ScrapedImgDir(rom.OutputDir), romName+".png")
+			targets, files := pendingMedia(rom, romName, events, count)
+			if len(targets) == 0 {
+				exportRom(rom, scrapedGame(rom), files)
+				continue
+			}
 
-			if hasScrapedImage(scrapeFile) {
-				if !config.IgnoreSkippedRomMessage {
-					events <- fmt.Sprintf("Skipping %s: image already scraped", romName)
+			override, hasOverride := lookupOverride(rom.Path, rom.Overrides, config.OverridesFile)
+			if hasOverride && override.Image != "" {
+				// The override image stands for the first media entry only
+				if target := targets[0]; target.index == 0 {
+					if path, err := scraper.UseOverrideImage(override.Image, target.media, target.dest, target.existing); err != nil {
+						events <- fmt.Sprintf("Error scraping %s: %v", target.label, err)
+						count.failed.Add(1)
+					} else {
+						events <- fmt.Sprintf("Scraped %s (from override image)", target.label)
+						count.success.Add(1)
+						files[target.index] = export.File{Type: target.media.Type[0], Path: path}
+					}
+					targets = targets[1:]
+				}
+				hasOverride = override.GameID != ""
+				if len(targets) == 0 {
+					exportRom(rom, scrapedGame(rom), files)
+					continue
 				}
-				count.skipped.Add(1)
-				continue
 			}
 
-			if res, err := findGame(ctx, rom.SystemID, rom.Name); err != nil {
+			provider, err := getProvider(rom.Provider)
+			if err != nil {
+				events <- fmt.Sprintf("Error scraping %s: %v", romName, err)
+				count.failed.Add(uint32(len(targets)))
+				break download
+			}
+
+			var game scraper.Game
+			if hasOverride {
+				// Override ids are ScreenScraper game ids, whatever the system provider
+				provider = scraper.ScreenScraper{}
+				game, err = scraper.GameByID(ctx, rom.SystemID, override.GameID)
+				game.MatchedBy = scraper.MatchedByOverride
+			} else {
+				game, err = identifyGame(ctx, provider, rom)
+			}
+
+			if err != nil {
 				if errors.Is(err, scraper.HTTPRequestAbortedErr) {
 					break download
 				}
+				if errors.Is(err, matchSkippedErr) {
+					events <- fmt.Sprintf("Skipping %s: %v", romName, err)
+					count.skipped.Add(uint32(len(targets)))
+					continue
+				}
+				count.failed.Add(uint32(len(targets)))
+				if scraper.IsFatal(err) {
+					stop.trip(err)
+					break download
+				}
 				events <- fmt.Sprintf("Error scraping %s: %v", romName, err)
-				count.failed.Add(1)
-			} else {
+				continue
+			}
 
-				if err := downloadMedia(ctx, res.Response.Jeu.Medias, scraper.MediaType(config.Media.Type), scrapeFile); err != nil {
-					events <- fmt.Sprintf("Error scraping %s: %v", romName, err)
+			for _, target := range targets {
+				if media, err := fetchTarget(ctx, provider, game, target); err != nil {
+					if errors.Is(err, scraper.HTTPRequestAbortedErr) {
+						break download
+					}
+					if errors.Is(err, scraper.MediaUnchangedErr) {
+						if !config.IgnoreSkippedRomMessage {
+							events <- fmt.Sprintf("Skipping %s: image unchanged on the server", target.label)
+						}
+						count.skipped.Add(1)
+						continue
+					}
 					count.failed.Add(1)
+					if scraper.IsFatal(err) {
+						stop.trip(err)
+						break download
+					}
+					events <- fmt.Sprintf("Error scraping %s: %v", target.label, err)
 					if errors.Is(err, scraper.UnknownMediaTypeErr) {
 						break download
 					}
 				} else {
-					events <- "Scraped " + romName
+					events <- scrapedMessage(target, game, media)
 					count.success.Add(1)
+					files[target.index] = export.File{Type: media.Type, Path: scraper.MediaPath(target.dest, media)}
+					if err := writeSidecar(files[target.index].Path, game, media); err != nil {
+						events <- fmt.Sprintf("Error saving the metadata of %s: %v", target.label, err)
+					}
 				}
 			}
+			exportRom(rom, game, files)
 		}
 	}
 }
+
+// fetchTarget downloads the media of a target, over the file of a previous
+// run when it refreshes one.
+func fetchTarget(ctx context.Context, provider scraper.Provider, game scraper.Game, target mediaTarget) (scraper.Media, error) {
+	if target.existing == "" {
+		return downloadMedia(ctx, provider, game, target.media, target.dest)
+	}
+	onlyChanged := config.Refresh.Mode == scraper.RefreshChanged
+	return refreshMedia(ctx, provider, game, target.media, target.dest, target.existing, onlyChanged)
+}
+
+// scrapedGame returns what previous runs learned about a rom that has nothing
+// left to scrape, so it is exported without querying the provider.
+func scrapedGame(rom Rom) scraper.Game {
+	if len(rom.Exports) == 0 || (rom.Provider != "" && rom.Provider != scraper.DefaultProvider) {
+		return scraper.Game{}
+	}
+	game, _ := cachedGame(rom.SystemID, rom.Path)
+	return game
+}
+
+// exportRom adds the rom to the frontend files written at the end of the run.
+func exportRom(rom Rom, game scraper.Game, files []export.File) {
+	if exports == nil || len(rom.Exports) == 0 {
+		return
+	}
+
+	entry := export.Entry{RomPath: rom.Path, Game: game}
+	for _, file := range files {
+		if file.Path != "" {
+			entry.Media = append(entry.Media, file)
+		}
+	}
+	exports.Add(export.System{Dir: rom.SystemDir, Name: rom.SystemName, Playlist: rom.Playlist}, rom.Exports, entry)
+}
+
+// writeSidecar saves the game metadata next to the images when sidecars are
+// enabled.
+func writeSidecar(path string, game scraper.Game, media scraper.Media) error {
+	if !config.Sidecars || scraper.MediaType(media.Type).Format() != scraper.ImageFormat {
+		return nil
+	}
+	return scraper.WriteSidecar(path, game, media)
+}
+
+// scrapedMessage reports the media type used when the entry has fallbacks and
+// how the game was identified when it was not by hash.
+func scrapedMessage(target mediaTarget, game scraper.Game, media scraper.Media) string {
+	msg := "Scraped " + target.label
+	if len(target.media.Type) > 1 {
+		msg += " using " + media.Type
+	}
+	if game.MatchedBy != "" && game.MatchedBy != scraper.MatchedByHash {
+		msg += fmt.Sprintf(" (matched by %s as %s)", game.MatchedBy, game.Name)
+	}
+	return msg
+}
+
+// mediaTarget is a media entry still missing for a rom. dest has no
+// extension, it depends on the media type found.
+type mediaTarget struct {
+	media config.ScrapeMedia
+	dest  string
+	label string
+	// index is the position of the entry in the media config
+	index int
+	// existing is the file scraped by a previous run the target refreshes
+	existing string
+}
+
+// pendingMedia returns the media entries the rom still needs, or needs again
+// under the refresh mode, counting and reporting the ones already scraped. The files already scraped are returned
+// at the position of their entry.
+func pendingMedia(rom Rom, romName string, events chan<- string, count *counter) ([]mediaTarget, []export.File) {
+	targets := make([]mediaTarget, 0, len(config.Media))
+	files := make([]export.File, len(config.Media))
+	for i, media := range config.Media {
+		target := mediaTarget{
+			media: media,
+			dest:  filepath.Join(config.ScrapedImgDir(media, rom.OutputDir), romName),
+			label: romName,
+			index: i,
+		}
+		if len(config.Media) > 1 {
+			target.label = fmt.Sprintf("%s [%s]", romName, media.Type)
+		}
+
+		if path, ok := scrapedFile(target); ok {
+			// Kept as the file of the entry unless the refresh replaces it
+			files[i] = export.File{Type: media.Type[0], Path: path}
+			if !needsRefresh(path, time.Now()) {
+				if !config.IgnoreSkippedRomMessage {
+					events <- fmt.Sprintf("Skipping %s: image already scraped", target.label)
+				}
+				count.skipped.Add(1)
+				continue
+			}
+			target.existing = path
+		}
+		targets = append(targets, target)
+	}
+
+	return targets, files
+}
+
+// scrapedFile returns the file the rom already has for the media entry,
+// whatever the type of the entry it was scraped with.
+func scrapedFile(target mediaTarget) (string, bool) {
+	for _, ext := range scraper.MediaExtensions(target.media.Type) {
+		if hasScrapedImage(target.dest + ext) {
+			return target.dest + ext, true
+		}
+	}
+	return "", false
+}
+
+// identifyGame asks the user to pick the game when the provider finds several
+// candidates for the rom, and remembers the choice for the next runs.
+func identifyGame(ctx context.Context, provider scraper.Provider, rom Rom) (scraper.Game, error) {
+	game, err := provider.IdentifyGame(ctx, rom.SystemID, rom.Path)
+
+	var ambiguous *scraper.AmbiguousMatchError
+	if !errors.As(err, &ambiguous) || pickMatch == nil {
+		return game, err
+	}
+
+	candidate, ok := pickMatch(ctx, rom, ambiguous.Candidates)
+	if !ok {
+		if ctx.Err() != nil {
+			return scraper.Game{}, scraper.HTTPRequestAbortedErr
+		}
+		return scraper.Game{}, matchSkippedErr
+	}
+	saveChoice(rom.SystemID, rom.Path, candidate.ID)
+	candidate.MatchedBy = scraper.MatchedByChoice
+
+	return candidate.Game, nil
+}
@@ -3,8 +3,10 @@ package screens
 import (
 	"context"
 	"errors"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -12,6 +14,7 @@ import (
 	"time"
 
 	"github.com/anibaldeboni/screech/config"
+	"github.com/anibaldeboni/screech/export"
 	"github.com/anibaldeboni/screech/scraper"
 )
 
@@ -98,7 +101,7 @@ func TestFindRoms(t *testing.T) {
 			defer cancel()
 
 			events := make(chan string, 10)
-			roms := findRoms(ctx, events, []romDirSettings{dir}, tt.maxDepth)
+			roms, scanErrors := findRoms(ctx, events, []romDirSettings{dir}, tt.maxDepth)
 
 			var result []string
 			for rom := range roms {
@@ -125,6 +128,13 @@ func TestFindRoms(t *testing.T) {
 					t.Error("expected error event, but no event received")
 				}
 			}
+			var expected uint32
+			if tt.expectErr {
+				expected = 1
+			}
+			if scanErrors.Load() != expected {
+				t.Errorf("expected %d scan errors, got %d", expected, scanErrors.Load())
+			}
 		})
 	}
 }
@@ -133,8 +143,8 @@ func TestWorker(t *testing.T) {
 	tests := []struct {
 		name                string
 		roms                []Rom
-		findGameFunc        func(ctx context.Context, systemID string, romPath string) (scraper.GameInfoResponse, error)
-		downloadMediaFunc   func(context.Context, []scraper.Media, scraper.MediaType, string) error
+		identifyGameFunc    func(ctx context.Context, systemID string, romPath string) (scraper.Game, error)
+		downloadMediaFunc   func(context.Context, scraper.Provider, scraper.Game, config.ScrapeMedia, string) (scraper.Media, error)
 		hasScrapedImageFunc func(string) bool
 		expectedEvents      []string
 		expectedCounts      counter
@@ -144,11 +154,11 @@ func TestWorker(t *testing.T) {
 			roms: []Rom{
 				{Name: "game1.rom", Path: "game1.rom", OutputDir: "output", SystemID: "1"},
 			},
-			findGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.GameInfoResponse, error) {
-				return scraper.GameInfoResponse{}, nil
+			identifyGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.Game, error) {
+				return scraper.Game{}, nil
 			},
-			downloadMediaFunc: func(ctx context.Context, medias []scraper.Media, mediaType scraper.MediaType, dest string) error {
-				return nil
+			downloadMediaFunc: func(ctx context.Context, provider scraper.Provider, game scraper.Game, media config.ScrapeMedia, dest string) (scraper.Media, error) {
+				return scraper.Media{}, nil
 			},
 			hasScrapedImageFunc: func(rom string) bool {
 				return false
@@ -161,11 +171,11 @@ func TestWorker(t *testing.T) {
 			roms: []Rom{
 				{Name: "game1.txt", Path: "game1.txt", OutputDir: "output", SystemID: "1"},
 			},
-			findGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.GameInfoResponse, error) {
-				return scraper.GameInfoResponse{}, nil
+			identifyGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.Game, error) {
+				return scraper.Game{}, nil
 			},
-			downloadMediaFunc: func(ctx context.Context, medias []scraper.Media, mediaType scraper.MediaType, dest string) error {
-				return nil
+			downloadMediaFunc: func(ctx context.Context, provider scraper.Provider, game scraper.Game, media config.ScrapeMedia, dest string) (scraper.Media, error) {
+				return scraper.Media{}, nil
 			},
 			hasScrapedImageFunc: func(rom string) bool {
 				return false
@@ -178,11 +188,11 @@ func TestWorker(t *testing.T) {
 			roms: []Rom{
 				{Name: "game1.rom", Path: "game1.rom", OutputDir: "output", SystemID: "1"},
 			},
-			findGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.GameInfoResponse, error) {
-				return scraper.GameInfoResponse{}, nil
+			identifyGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.Game, error) {
+				return scraper.Game{}, nil
 			},
-			downloadMediaFunc: func(ctx context.Context, medias []scraper.Media, mediaType scraper.MediaType, dest string) error {
-				return nil
+			downloadMediaFunc: func(ctx context.Context, provider scraper.Provider, game scraper.Game, media config.ScrapeMedia, dest string) (scraper.Media, error) {
+				return scraper.Media{}, nil
 			},
 			hasScrapedImageFunc: func(rom string) bool {
 				return true
@@ -195,11 +205,11 @@ func TestWorker(t *testing.T) {
 			roms: []Rom{
 				{Name: "game1.rom", Path: "game1.rom", OutputDir: "output", SystemID: "1"},
 			},
-			findGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.GameInfoResponse, error) {
-				return scraper.GameInfoResponse{}, errors.New("scraping error")
+			identifyGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.Game, error) {
+				return scraper.Game{}, errors.New("scraping error")
 			},
-			downloadMediaFunc: func(ctx context.Context, medias []scraper.Media, mediaType scraper.MediaType, dest string) error {
-				return nil
+			downloadMediaFunc: func(ctx context.Context, provider scraper.Provider, game scraper.Game, media config.ScrapeMedia, dest string) (scraper.Media, error) {
+				return scraper.Media{}, nil
 			},
 			hasScrapedImageFunc: func(rom string) bool {
 				return false
@@ -226,22 +236,22 @@ func TestWorker(t *testing.T) {
 
 			count := counter{success: new(atomic.Uint32), failed: new(atomic.Uint32), skipped: new(atomic.Uint32)}
 
-			originalFindGame := findGame
+			originalGetProvider := getProvider
 			originalDownloadMedia := downloadMedia
 			originalHasScrapedImage := hasScrapedImage
 			defer func() {
-				findGame = originalFindGame
+				getProvider = originalGetProvider
 				downloadMedia = originalDownloadMedia
 				hasScrapedImage = originalHasScrapedImage
 			}()
 
-			findGame = tt.findGameFunc
+			getProvider = stubProvider(tt.identifyGameFunc)
 			downloadMedia = tt.downloadMediaFunc
 			hasScrapedImage = tt.hasScrapedImageFunc
 
 			config.ExcludeExtensions = []string{".txt"}
 
-			go worker(ctx, &wg, roms, events, &count)
+			go worker(ctx, &wg, roms, events, &count, &breaker{cancel: cancel})
 
 			wg.Wait()
 			close(events)
@@ -278,8 +288,8 @@ func TestBuildWorkerPool(t *testing.T) {
 	tests := []struct {
 		name                string
 		roms                []Rom
-		findGameFunc        func(ctx context.Context, systemID string, romPath string) (scraper.GameInfoResponse, error)
-		downloadMediaFunc   func(context.Context, []scraper.Media, scraper.MediaType, string) error
+		identifyGameFunc    func(ctx context.Context, systemID string, romPath string) (scraper.Game, error)
+		downloadMediaFunc   func(context.Context, scraper.Provider, scraper.Game, config.ScrapeMedia, string) (scraper.Media, error)
 		hasScrapedImageFunc func(string) bool
 		expectedEvents      []string
 		expectedCounts      counter
@@ -290,11 +300,11 @@ func TestBuildWorkerPool(t *testing.T) {
 				{Name: "game1.rom", Path: "game1.rom", OutputDir: "output", SystemID: "1"},
 				{Name: "game2.rom", Path: "game2.rom", OutputDir: "output", SystemID: "1"},
 			},
-			findGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.GameInfoResponse, error) {
-				return scraper.GameInfoResponse{}, nil
+			identifyGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.Game, error) {
+				return scraper.Game{}, nil
 			},
-			downloadMediaFunc: func(ctx context.Context, medias []scraper.Media, mediaType scraper.MediaType, dest string) error {
-				return nil
+			downloadMediaFunc: func(ctx context.Context, provider scraper.Provider, game scraper.Game, media config.ScrapeMedia, dest string) (scraper.Media, error) {
+				return scraper.Media{}, nil
 			},
 			hasScrapedImageFunc: func(rom string) bool {
 				return false
@@ -308,11 +318,11 @@ func TestBuildWorkerPool(t *testing.T) {
 				{Name: "game1.rom", Path: "game1.rom", OutputDir: "output", SystemID: "1"},
 				{Name: "game2.txt", Path: "game2.txt", OutputDir: "output", SystemID: "1"},
 			},
-			findGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.GameInfoResponse, error) {
-				return scraper.GameInfoResponse{}, nil
+			identifyGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.Game, error) {
+				return scraper.Game{}, nil
 			},
-			downloadMediaFunc: func(ctx context.Context, medias []scraper.Media, mediaType scraper.MediaType, dest string) error {
-				return nil
+			downloadMediaFunc: func(ctx context.Context, provider scraper.Provider, game scraper.Game, media config.ScrapeMedia, dest string) (scraper.Media, error) {
+				return scraper.Media{}, nil
 			},
 			hasScrapedImageFunc: func(rom string) bool {
 				return false
@@ -326,11 +336,11 @@ func TestBuildWorkerPool(t *testing.T) {
 				{Name: "game1.rom", Path: "game1.rom", OutputDir: "output", SystemID: "1"},
 				{Name: "game2.rom", Path: "game2.rom", OutputDir: "output", SystemID: "1"},
 			},
-			findGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.GameInfoResponse, error) {
-				return scraper.GameInfoResponse{}, nil
+			identifyGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.Game, error) {
+				return scraper.Game{}, nil
 			},
-			downloadMediaFunc: func(ctx context.Context, medias []scraper.Media, mediaType scraper.MediaType, dest string) error {
-				return nil
+			downloadMediaFunc: func(ctx context.Context, provider scraper.Provider, game scraper.Game, media config.ScrapeMedia, dest string) (scraper.Media, error) {
+				return scraper.Media{}, nil
 			},
 			hasScrapedImageFunc: func(rom string) bool {
 				return true
@@ -343,11 +353,11 @@ func TestBuildWorkerPool(t *testing.T) {
 			roms: []Rom{
 				{Name: "game1.rom", Path: "game1.rom", OutputDir: "output", SystemID: "1"},
 			},
-			findGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.GameInfoResponse, error) {
-				return scraper.GameInfoResponse{}, errors.New("scraping error")
+			identifyGameFunc: func(ctx context.Context, systemID string, rom string) (scraper.Game, error) {
+				return scraper.Game{}, errors.New("scraping error")
 			},
-			downloadMediaFunc: func(ctx context.Context, medias []scraper.Media, mediaType scraper.MediaType, dest string) error {
-				return nil
+			downloadMediaFunc: func(ctx context.Context, provider scraper.Provider, game scraper.Game, media config.ScrapeMedia, dest string) (scraper.Media, error) {
+				return scraper.Media{}, nil
 			},
 			hasScrapedImageFunc: func(rom string) bool {
 				return false
@@ -370,16 +380,16 @@ func TestBuildWorkerPool(t *testing.T) {
 
 			events := make(chan string, 10)
 
-			originalFindGame := findGame
+			originalGetProvider := getProvider
 			originalDownloadMedia := downloadMedia
 			originalHasScrapedImage := hasScrapedImage
 			defer func() {
-				findGame = originalFindGame
+				getProvider = originalGetProvider
 				downloadMedia = originalDownloadMedia
 				hasScrapedImage = originalHasScrapedImage
 			}()
 
-			findGame = tt.findGameFunc
+			getProvider = stubProvider(tt.identifyGameFunc)
 			downloadMedia = tt.downloadMediaFunc
 			hasScrapedImage = tt.hasScrapedImageFunc
 
@@ -405,8 +415,473 @@ func TestBuildWorkerPool(t *testing.T) {
 	}
 }
 
+func TestBuildWorkerPoolStopsOnFatalError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	roms := make(chan Rom, 3)
+	for _, name := range []string{"game1.rom", "game2.rom", "game3.rom"} {
+		roms <- Rom{Name: name, Path: name, OutputDir: "output", SystemID: "1"}
+	}
+	close(roms)
+
+	originalGetProvider := getProvider
+	originalHasScrapedImage := hasScrapedImage
+	defer func() {
+		getProvider = originalGetProvider
+		hasScrapedImage = originalHasScrapedImage
+	}()
+
+	var lookups atomic.Uint32
+	getProvider = stubProvider(func(ctx context.Context, systemID string, rom string) (scraper.Game, error) {
+		lookups.Add(1)
+		return scraper.Game{}, scraper.ScrapeQuotaErr
+	})
+	hasScrapedImage = func(string) bool { return false }
+
+	events := make(chan string, 10)
+	go buildWorkerPool(ctx, cancel, 1, roms, events)
+
+	var resultEvents []string
+	for event := range events {
+		resultEvents = append(resultEvents, event)
+	}
+
+	if lookups.Load() != 1 {
+		t.Errorf("expected the pool to stop after the first lookup, got %d lookups", lookups.Load())
+	}
+
+	if len(resultEvents) != 6 {
+		t.Fatalf("expected 6 events, got %d: %v", len(resultEvents), resultEvents)
+	}
+	if resultEvents[0] != "Scraping stopped!" || !strings.Contains(resultEvents[1], "quota") || !strings.HasPrefix(resultEvents[2], "The quota resets at") {
+		t.Errorf("expected a quota explanation, got %v", resultEvents)
+	}
+	if resultEvents[4] != "Failed: 1" {
+		t.Errorf("expected Failed: 1, got %s", resultEvents[4])
+	}
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Error("expected the context to be cancelled")
+	}
+}
+
 func newUint32(val uint32) *atomic.Uint32 {
 	v := atomic.Uint32{}
 	v.Store(val)
 	return &v
 }
+
+type fakeProvider struct {
+	identifyGame func(ctx context.Context, systemID string, romPath string) (scraper.Game, error)
+}
+
+func (f fakeProvider) IdentifyGame(ctx context.Context, systemID string, romPath string) (scraper.Game, error) {
+	return f.identifyGame(ctx, systemID, romPath)
+}
+
+func (fakeProvider) ListMedia(_ context.Context, game scraper.Game) ([]scraper.Media, error) {
+	return game.Medias, nil
+}
+
+func (fakeProvider) FetchMedia(context.Context, scraper.Media, string) error {
+	return nil
+}
+
+func stubProvider(identifyGame func(ctx context.Context, systemID string, romPath string) (scraper.Game, error)) func(string) (scraper.Provider, error) {
+	return func(string) (scraper.Provider, error) {
+		return fakeProvider{identifyGame: identifyGame}, nil
+	}
+}
+
+func TestCheckAccount(t *testing.T) {
+	originalValidateAccount := validateAccount
+	defer func() {
+		validateAccount = originalValidateAccount
+		config.Username = ""
+	}()
+
+	var validated bool
+	validateAccount = func(ctx context.Context) error {
+		validated = true
+		return scraper.InvalidCredentialsErr
+	}
+
+	tests := []struct {
+		name         string
+		username     string
+		systems      []romDirSettings
+		wantValidate bool
+	}{
+		{"anonymous", "", []romDirSettings{{Provider: ""}}, false},
+		{"libretro only", "player", []romDirSettings{{Provider: scraper.LibretroProvider}}, false},
+		{"default provider", "player", []romDirSettings{{Provider: scraper.LibretroProvider}, {Provider: ""}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validated = false
+			config.Username = tt.username
+
+			err := checkAccount(context.Background(), tt.systems)
+			if validated != tt.wantValidate {
+				t.Errorf("expected validation %v, got %v", tt.wantValidate, validated)
+			}
+			if tt.wantValidate && !errors.Is(err, scraper.InvalidCredentialsErr) {
+				t.Errorf("expected InvalidCredentialsErr, got %v", err)
+			}
+			if !tt.wantValidate && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWorkerOverrideImage(t *testing.T) {
+	dir := t.TempDir()
+	image := filepath.Join(dir, "cover.png")
+	_ = os.WriteFile(image, []byte("png"), 0644)
+
+	originalLookupOverride := lookupOverride
+	originalGetProvider := getProvider
+	originalHasScrapedImage := hasScrapedImage
+	originalBoxart := config.Boxart
+	defer func() {
+		lookupOverride = originalLookupOverride
+		getProvider = originalGetProvider
+		hasScrapedImage = originalHasScrapedImage
+		config.Boxart = originalBoxart
+	}()
+
+	lookupOverride = func(romPath string, files ...string) (scraper.Override, bool) {
+		return scraper.Override{Image: image}, true
+	}
+	getProvider = stubProvider(func(ctx context.Context, systemID string, romPath string) (scraper.Game, error) {
+		t.Error("the provider should not be called for an image override")
+		return scraper.Game{}, nil
+	})
+	hasScrapedImage = func(string) bool { return false }
+	config.Boxart.Dir = filepath.Join(dir, "%SYSTEM%")
+
+	roms := make(chan Rom, 1)
+	roms <- Rom{Name: "homebrew.rom", Path: "homebrew.rom", OutputDir: "Imgs", SystemID: "1"}
+	close(roms)
+
+	events := make(chan string, 10)
+	count := counter{success: new(atomic.Uint32), failed: new(atomic.Uint32), skipped: new(atomic.Uint32)}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	worker(context.Background(), &wg, roms, events, &count, &breaker{cancel: func() {}})
+	close(events)
+
+	if event := <-events; event != "Scraped homebrew (from override image)" {
+		t.Errorf("unexpected event %q", event)
+	}
+	if count.success.Load() != 1 {
+		t.Errorf("expected 1 success, got %d", count.success.Load())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Imgs", "homebrew.png")); err != nil {
+		t.Errorf("expected the override image to be copied: %v", err)
+	}
+}
+
+func TestWorkerMultipleMedia(t *testing.T) {
+	originalGetProvider := getProvider
+	originalDownloadMedia := downloadMedia
+	originalHasScrapedImage := hasScrapedImage
+	originalMedia := config.Media
+	defer func() {
+		getProvider = originalGetProvider
+		downloadMedia = originalDownloadMedia
+		hasScrapedImage = originalHasScrapedImage
+		config.Media = originalMedia
+	}()
+
+	config.Media = config.MediaList{
+		{Type: config.MediaTypes{"box-2D"}, Dir: "/Imgs/%SYSTEM%"},
+		{Type: config.MediaTypes{"ss"}, Dir: "/Screenshots/%SYSTEM%"},
+		{Type: config.MediaTypes{"sstitle"}, Dir: "/Titles/%SYSTEM%"},
+	}
+	config.IgnoreSkippedRomMessage = false
+
+	var lookups atomic.Uint32
+	getProvider = stubProvider(func(ctx context.Context, systemID string, romPath string) (scraper.Game, error) {
+		lookups.Add(1)
+		return scraper.Game{}, nil
+	})
+	var downloaded []string
+	downloadMedia = func(ctx context.Context, provider scraper.Provider, game scraper.Game, media config.ScrapeMedia, dest string) (scraper.Media, error) {
+		downloaded = append(downloaded, dest)
+		if media.Type[0] == "sstitle" {
+			return scraper.Media{}, errors.New("media not found for type: sstitle")
+		}
+		return scraper.Media{Type: media.Type[0]}, nil
+	}
+	hasScrapedImage = func(dest string) bool {
+		return strings.HasPrefix(dest, filepath.FromSlash("/Imgs"))
+	}
+
+	roms := make(chan Rom, 1)
+	roms <- Rom{Name: "game1.rom", Path: "game1.rom", OutputDir: "SFC", SystemID: "1"}
+	close(roms)
+
+	events := make(chan string, 10)
+	count := counter{success: new(atomic.Uint32), failed: new(atomic.Uint32), skipped: new(atomic.Uint32)}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	worker(context.Background(), &wg, roms, events, &count, &breaker{cancel: func() {}})
+	close(events)
+
+	var resultEvents []string
+	for event := range events {
+		resultEvents = append(resultEvents, event)
+	}
+	expectedEvents := []string{
+		"Skipping game1 [box-2D]: image already scraped",
+		"Scraped game1 [ss]",
+		"Error scraping game1 [sstitle]: media not found for type: sstitle",
+	}
+	if !slices.Equal(resultEvents, expectedEvents) {
+		t.Errorf("expected events %q, got %q", expectedEvents, resultEvents)
+	}
+
+	if lookups.Load() != 1 {
+		t.Errorf("expected a single game lookup, got %d", lookups.Load())
+	}
+	if len(downloaded) != 2 || downloaded[0] != filepath.FromSlash("/Screenshots/SFC/game1") {
+		t.Errorf("unexpected downloads %v", downloaded)
+	}
+	if count.success.Load() != 1 || count.failed.Load() != 1 || count.skipped.Load() != 1 {
+		t.Errorf("expected 1 success, 1 failed and 1 skipped, got %d, %d and %d", count.success.Load(), count.failed.Load(), count.skipped.Load())
+	}
+}
+
+func TestWorkerRefresh(t *testing.T) {
+	originalGetProvider := getProvider
+	originalDownloadMedia := downloadMedia
+	originalRefreshMedia := refreshMedia
+	originalNeedsRefresh := needsRefresh
+	originalHasScrapedImage := hasScrapedImage
+	originalMedia := config.Media
+	originalRefresh := config.Refresh
+	defer func() {
+		getProvider = originalGetProvider
+		downloadMedia = originalDownloadMedia
+		refreshMedia = originalRefreshMedia
+		needsRefresh = originalNeedsRefresh
+		hasScrapedImage = originalHasScrapedImage
+		config.Media = originalMedia
+		config.Refresh = originalRefresh
+	}()
+
+	config.Media = config.MediaList{
+		{Type: config.MediaTypes{"box-2D"}, Dir: "/Imgs/%SYSTEM%"},
+		{Type: config.MediaTypes{"ss"}, Dir: "/Screenshots/%SYSTEM%"},
+		{Type: config.MediaTypes{"wheel"}, Dir: "/Wheels/%SYSTEM%"},
+		{Type: config.MediaTypes{"sstitle"}, Dir: "/Titles/%SYSTEM%"},
+	}
+	config.Refresh.Mode = scraper.RefreshChanged
+	config.IgnoreSkippedRomMessage = false
+
+	getProvider = stubProvider(func(ctx context.Context, systemID string, romPath string) (scraper.Game, error) {
+		return scraper.Game{}, nil
+	})
+	downloadMedia = func(ctx context.Context, provider scraper.Provider, game scraper.Game, media config.ScrapeMedia, dest string) (scraper.Media, error) {
+		return scraper.Media{Type: media.Type[0]}, nil
+	}
+	refreshed := make(map[string]bool)
+	refreshMedia = func(ctx context.Context, provider scraper.Provider, game scraper.Game, media config.ScrapeMedia, dest, existing string, onlyChanged bool) (scraper.Media, error) {
+		refreshed[existing] = onlyChanged
+		if media.Type[0] == "ss" {
+			return scraper.Media{}, scraper.MediaUnchangedErr
+		}
+		return scraper.Media{Type: media.Type[0]}, nil
+	}
+	// Every media but the titles was scraped, the wheels are up to date
+	hasScrapedImage = func(dest string) bool {
+		return !strings.HasPrefix(dest, filepath.FromSlash("/Titles")) && strings.HasSuffix(dest, ".png")
+	}
+	needsRefresh = func(path string, now time.Time) bool {
+		return !strings.HasPrefix(path, filepath.FromSlash("/Wheels"))
+	}
+
+	roms := make(chan Rom, 1)
+	roms <- Rom{Name: "game1.rom", Path: "game1.rom", OutputDir: "SFC", SystemID: "1"}
+	close(roms)
+
+	events := make(chan string, 10)
+	count := counter{success: new(atomic.Uint32), failed: new(atomic.Uint32), skipped: new(atomic.Uint32)}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	worker(context.Background(), &wg, roms, events, &count, &breaker{cancel: func() {}})
+	close(events)
+
+	var resultEvents []string
+	for event := range events {
+		resultEvents = append(resultEvents, event)
+	}
+	expectedEvents := []string{
+		"Skipping game1 [wheel]: image already scraped",
+		"Scraped game1 [box-2D]",
+		"Skipping game1 [ss]: image unchanged on the server",
+		"Scraped game1 [sstitle]",
+	}
+	if !slices.Equal(resultEvents, expectedEvents) {
+		t.Errorf("expected events %q, got %q", expectedEvents, resultEvents)
+	}
+
+	expectedRefreshed := map[string]bool{
+		filepath.FromSlash("/Imgs/SFC/game1.png"):        true,
+		filepath.FromSlash("/Screenshots/SFC/game1.png"): true,
+	}
+	if !maps.Equal(refreshed, expectedRefreshed) {
+		t.Errorf("expected refreshed files %v, got %v", expectedRefreshed, refreshed)
+	}
+	if count.success.Load() != 2 || count.skipped.Load() != 2 {
+		t.Errorf("expected 2 success and 2 skipped, got %d and %d", count.success.Load(), count.skipped.Load())
+	}
+}
+
+func TestScrapedMessage(t *testing.T) {
+	single := mediaTarget{label: "game1", media: config.ScrapeMedia{Type: config.MediaTypes{"box-3D"}}}
+	fallback := mediaTarget{label: "game1", media: config.ScrapeMedia{Type: config.MediaTypes{"box-3D", "box-2D"}}}
+
+	tests := []struct {
+		target   mediaTarget
+		game     scraper.Game
+		expected string
+	}{
+		{single, scraper.Game{MatchedBy: scraper.MatchedByHash}, "Scraped game1"},
+		{fallback, scraper.Game{}, "Scraped game1 using box-2D"},
+		{fallback, scraper.Game{Name: "Game One", MatchedBy: scraper.MatchedBySearch}, "Scraped game1 using box-2D (matched by search as Game One)"},
+	}
+
+	for _, tt := range tests {
+		if msg := scrapedMessage(tt.target, tt.game, scraper.Media{Type: "box-2D"}); msg != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, msg)
+		}
+	}
+}
+
+func TestWorkerExportsGamelist(t *testing.T) {
+	dir := t.TempDir()
+
+	originalGetProvider := getProvider
+	originalDownloadMedia := downloadMedia
+	originalHasScrapedImage := hasScrapedImage
+	originalMedia := config.Media
+	originalBoxart := config.Boxart
+	defer func() {
+		getProvider = originalGetProvider
+		downloadMedia = originalDownloadMedia
+		hasScrapedImage = originalHasScrapedImage
+		config.Media = originalMedia
+		config.Boxart = originalBoxart
+		exports = nil
+	}()
+
+	config.Media = config.MediaList{
+		{Type: config.MediaTypes{"box-3D"}},
+		{Type: config.MediaTypes{"ss"}, Dir: filepath.Join(dir, "Screenshots")},
+	}
+	config.Boxart.Dir = filepath.Join(dir, "Imgs")
+	getProvider = stubProvider(func(ctx context.Context, systemID string, romPath string) (scraper.Game, error) {
+		return scraper.Game{ID: "42", Name: "Game One", Metadata: scraper.Metadata{Publisher: "Nintendo"}}, nil
+	})
+	downloadMedia = func(ctx context.Context, provider scraper.Provider, game scraper.Game, media config.ScrapeMedia, dest string) (scraper.Media, error) {
+		return scraper.Media{Type: media.Type[0]}, nil
+	}
+	// The screenshot was scraped in a previous run
+	hasScrapedImage = func(dest string) bool {
+		return dest == filepath.Join(dir, "Screenshots", "game1.png")
+	}
+	exports = export.NewBatch()
+
+	roms := make(chan Rom, 1)
+	roms <- Rom{Name: "game1.sfc", Path: filepath.Join(dir, "game1.sfc"), SystemDir: dir, SystemID: "1", Exports: []string{"gamelist"}}
+	close(roms)
+
+	events := make(chan string, 10)
+	count := counter{success: new(atomic.Uint32), failed: new(atomic.Uint32), skipped: new(atomic.Uint32)}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	worker(context.Background(), &wg, roms, events, &count, &breaker{cancel: func() {}})
+
+	if err := exports.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, export.GamelistFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`<game id="42" source="ScreenScraper.fr">`,
+		"<path>./game1.sfc</path>",
+		"<name>Game One</name>",
+		"<image>./Imgs/game1.png</image>",
+		"<thumbnail>./Screenshots/game1.png</thumbnail>",
+		"<publisher>Nintendo</publisher>",
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected the gamelist to contain %s, got:\n%s", expected, data)
+		}
+	}
+}
+
+func TestWorkerExportsScrapedRom(t *testing.T) {
+	dir := t.TempDir()
+
+	originalGetProvider := getProvider
+	originalHasScrapedImage := hasScrapedImage
+	originalCachedGame := cachedGame
+	originalMedia := config.Media
+	originalBoxart := config.Boxart
+	defer func() {
+		getProvider = originalGetProvider
+		hasScrapedImage = originalHasScrapedImage
+		cachedGame = originalCachedGame
+		config.Media = originalMedia
+		config.Boxart = originalBoxart
+		exports = nil
+	}()
+
+	config.Media = config.MediaList{{Type: config.MediaTypes{"box-3D"}}}
+	config.Boxart.Dir = filepath.Join(dir, "Imgs")
+	getProvider = stubProvider(func(ctx context.Context, systemID string, romPath string) (scraper.Game, error) {
+		t.Error("expected a fully scraped rom not to be identified again")
+		return scraper.Game{}, nil
+	})
+	hasScrapedImage = func(dest string) bool { return true }
+	cachedGame = func(systemID, romPath string) (scraper.Game, bool) {
+		return scraper.Game{ID: "42", Name: "Game One"}, true
+	}
+	exports = export.NewBatch()
+
+	roms := make(chan Rom, 1)
+	roms <- Rom{Name: "game1.sfc", Path: filepath.Join(dir, "game1.sfc"), SystemDir: dir, SystemID: "1", Exports: []string{"gamelist"}}
+	close(roms)
+
+	events := make(chan string, 10)
+	count := counter{success: new(atomic.Uint32), failed: new(atomic.Uint32), skipped: new(atomic.Uint32)}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	worker(context.Background(), &wg, roms, events, &count, &breaker{cancel: func() {}})
+
+	if err := exports.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, export.GamelistFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`<game id="42" source="ScreenScraper.fr">`,
+		"<name>Game One</name>",
+		"<image>./Imgs/game1.png</image>",
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected the gamelist to contain %s, got:\n%s", expected, data)
+		}
+	}
+}